@@ -7,8 +7,9 @@ import (
 )
 
 var (
-	Type             = component.MustNewType("nats")
-	TracesStability  = component.StabilityLevelBeta
-	MetricsStability = component.StabilityLevelBeta
-	LogsStability    = component.StabilityLevelBeta
+	Type               = component.MustNewType("nats")
+	TracesStability    = component.StabilityLevelBeta
+	MetricsStability   = component.StabilityLevelBeta
+	LogsStability      = component.StabilityLevelBeta
+	ExtensionStability = component.StabilityLevelAlpha
 )
@@ -0,0 +1,9 @@
+package nats
+
+// Claim-check headers. A message carrying them has an empty body; its payload
+// is stored as an object in a JetStream Object Store bucket. All other headers
+// (Content-Type, Content-Encoding) describe the stored payload.
+const (
+	HeaderClaimCheckBucket = "Otel-Claim-Check-Bucket"
+	HeaderClaimCheckObject = "Otel-Claim-Check-Object"
+)
@@ -0,0 +1,134 @@
+package nats
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"go.opentelemetry.io/collector/config/configcompression"
+)
+
+// HeaderContentEncoding records the compression algorithm applied to the
+// message payload. Absent, empty or "identity" means uncompressed.
+const HeaderContentEncoding = "Content-Encoding"
+
+// ErrDecompressedSizeExceeded is returned when a decompressed payload would
+// exceed the configured size limit.
+var ErrDecompressedSizeExceeded = errors.New("decompressed payload exceeds size limit")
+
+// zstdEncoder is shared by all exporters; EncodeAll is safe for concurrent use.
+var zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+	return zstd.NewWriter(nil)
+})
+
+// ValidateCompression checks that the compression type is supported.
+func ValidateCompression(compression configcompression.Type) error {
+	switch compression {
+	case "", "none", configcompression.TypeGzip, configcompression.TypeZstd, configcompression.TypeSnappy:
+		return nil
+	default:
+		return fmt.Errorf("unsupported compression %q (must be gzip, zstd, snappy or none)", compression)
+	}
+}
+
+// Compress compresses data with the given algorithm.
+// Snappy uses the block format, matching the OTLP/HTTP snappy encoding.
+func Compress(compression configcompression.Type, data []byte) ([]byte, error) {
+	switch compression {
+	case configcompression.TypeGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case configcompression.TypeZstd:
+		enc, err := zstdEncoder()
+		if err != nil {
+			return nil, err
+		}
+		return enc.EncodeAll(data, nil), nil
+	case configcompression.TypeSnappy:
+		return snappy.Encode(nil, data), nil
+	case "", "none":
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", compression)
+	}
+}
+
+// Decompressor decompresses message payloads according to their
+// Content-Encoding header, enforcing a limit on the decompressed size
+// to protect against decompression bombs.
+type Decompressor struct {
+	maxSize int
+	zstd    *zstd.Decoder
+}
+
+// NewDecompressor creates a Decompressor that rejects payloads which
+// decompress to more than maxSize bytes.
+func NewDecompressor(maxSize int) (*Decompressor, error) {
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(maxSize)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd decoder: %w", err)
+	}
+	return &Decompressor{maxSize: maxSize, zstd: dec}, nil
+}
+
+// MaxSize returns the size in bytes above which payloads are rejected.
+func (d *Decompressor) MaxSize() int {
+	return d.maxSize
+}
+
+// Decompress returns the decompressed payload for the given Content-Encoding.
+// Uncompressed payloads are returned as is.
+func (d *Decompressor) Decompress(encoding string, data []byte) ([]byte, error) {
+	switch encoding {
+	case "", "identity":
+		return data, nil
+	case string(configcompression.TypeGzip):
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		out, err := io.ReadAll(io.LimitReader(r, int64(d.maxSize)+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(out) > d.maxSize {
+			return nil, ErrDecompressedSizeExceeded
+		}
+		return out, nil
+	case string(configcompression.TypeZstd):
+		out, err := d.zstd.DecodeAll(data, nil)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+			return nil, ErrDecompressedSizeExceeded
+		}
+		return out, err
+	case string(configcompression.TypeSnappy):
+		n, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if n > d.maxSize {
+			return nil, ErrDecompressedSizeExceeded
+		}
+		return snappy.Decode(nil, data)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// Close releases resources held by the Decompressor.
+func (d *Decompressor) Close() {
+	d.zstd.Close()
+}
@@ -0,0 +1,85 @@
+package nats
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/config/configcompression"
+)
+
+func TestCompressDecompress(t *testing.T) {
+	data := bytes.Repeat([]byte("otel over nats "), 1000)
+
+	dec, err := NewDecompressor(1 << 20)
+	require.NoError(t, err)
+	defer dec.Close()
+
+	for _, compression := range []configcompression.Type{
+		configcompression.TypeGzip,
+		configcompression.TypeZstd,
+		configcompression.TypeSnappy,
+	} {
+		t.Run(string(compression), func(t *testing.T) {
+			compressed, err := Compress(compression, data)
+			require.NoError(t, err)
+			assert.Less(t, len(compressed), len(data))
+
+			got, err := dec.Decompress(string(compression), compressed)
+			require.NoError(t, err)
+			assert.Equal(t, data, got)
+		})
+	}
+}
+
+func TestDecompress_Identity(t *testing.T) {
+	dec, err := NewDecompressor(16)
+	require.NoError(t, err)
+	defer dec.Close()
+
+	data := []byte("uncompressed")
+	for _, encoding := range []string{"", "identity"} {
+		got, err := dec.Decompress(encoding, data)
+		require.NoError(t, err)
+		assert.Equal(t, data, got)
+	}
+}
+
+func TestDecompress_SizeLimit(t *testing.T) {
+	data := make([]byte, 64<<10)
+
+	dec, err := NewDecompressor(1 << 10)
+	require.NoError(t, err)
+	defer dec.Close()
+
+	for _, compression := range []configcompression.Type{
+		configcompression.TypeGzip,
+		configcompression.TypeZstd,
+		configcompression.TypeSnappy,
+	} {
+		t.Run(string(compression), func(t *testing.T) {
+			compressed, err := Compress(compression, data)
+			require.NoError(t, err)
+
+			_, err = dec.Decompress(string(compression), compressed)
+			assert.ErrorIs(t, err, ErrDecompressedSizeExceeded)
+		})
+	}
+}
+
+func TestDecompress_UnsupportedEncoding(t *testing.T) {
+	dec, err := NewDecompressor(1 << 10)
+	require.NoError(t, err)
+	defer dec.Close()
+
+	_, err = dec.Decompress("br", []byte("data"))
+	assert.ErrorContains(t, err, `unsupported content encoding "br"`)
+}
+
+func TestValidateCompression(t *testing.T) {
+	for _, compression := range []configcompression.Type{"", "none", "gzip", "zstd", "snappy"} {
+		assert.NoError(t, ValidateCompression(compression))
+	}
+	assert.Error(t, ValidateCompression("lz4"))
+}
@@ -34,6 +34,11 @@ type ClientConfig struct {
 	// MaxReconnects is the maximum number of reconnection attempts.
 	// -1 means unlimited (default).
 	MaxReconnects int `mapstructure:"max_reconnects"`
+
+	// RetryOnFailedInitialConnect keeps connecting in the background, like
+	// a reconnect, if the server is not reachable at start. Components then
+	// start without a connection and finish starting once it is established.
+	RetryOnFailedInitialConnect bool `mapstructure:"retry_on_failed_initial_connect,omitempty"`
 }
 
 // AuthConfig holds NATS authentication options.
@@ -2,15 +2,68 @@ package nats
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/url"
 
 	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
+	"go.opentelemetry.io/collector/extension"
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrNotConnected is returned and reported while a component waits for
+	// its initial connection.
+	ErrNotConnected = errors.New("not connected to NATS")
+
+	errDisconnected = errors.New("disconnected from NATS")
+	errLameDuck     = errors.New("NATS server entered lame duck mode")
+)
+
+// ConnectionExtension is implemented by the nats extension, which owns a
+// single connection shared by all components referencing it.
+type ConnectionExtension interface {
+	extension.Extension
+
+	// Conn returns the shared connection. It is valid once the extension
+	// has started and until it shuts down.
+	Conn() *nats.Conn
+}
+
+// GetConnection returns the connection owned by the referenced extension if
+// id is set, or establishes a new connection from cfg otherwise.
+// owned reports whether the caller is responsible for closing the connection.
+func GetConnection(ctx context.Context, host component.Host, id *component.ID, cfg ClientConfig, logger *zap.Logger) (conn *nats.Conn, owned bool, err error) {
+	if id == nil {
+		conn, err = Connect(ctx, cfg, logger, StatusOptions(host, logger)...)
+		return conn, true, err
+	}
+
+	ext, ok := host.GetExtensions()[*id]
+	if !ok {
+		return nil, false, fmt.Errorf("connection extension %q not found", id)
+	}
+	connExt, ok := ext.(ConnectionExtension)
+	if !ok {
+		return nil, false, fmt.Errorf("extension %q is not a NATS connection", id)
+	}
+	return connExt.Conn(), false, nil
+}
+
+// ConnectionField identifies the connection a component uses in log entries:
+// the referenced extension if id is set, or the configured URL otherwise.
+func ConnectionField(id *component.ID, cfg ClientConfig) zap.Field {
+	if id != nil {
+		return zap.Stringer("connection", id)
+	}
+	return zap.String("url", cfg.URL)
+}
+
 // Connect establishes a NATS connection with the given configuration.
-func Connect(ctx context.Context, cfg ClientConfig, logger *zap.Logger) (*nats.Conn, error) {
+// Additional options are applied after the defaults and may override them.
+func Connect(ctx context.Context, cfg ClientConfig, logger *zap.Logger, extraOpts ...nats.Option) (*nats.Conn, error) {
 	opts := []nats.Option{
 		nats.Name("otel-collector"),
 		nats.Timeout(cfg.ConnectionTimeout),
@@ -48,11 +101,22 @@ func Connect(ctx context.Context, cfg ClientConfig, logger *zap.Logger) (*nats.C
 		opts = append(opts, nats.Secure(tlsConfig))
 	}
 
+	if cfg.RetryOnFailedInitialConnect {
+		opts = append(opts, nats.RetryOnFailedConnect(true))
+	}
+
+	opts = append(opts, extraOpts...)
+
 	conn, err := nats.Connect(cfg.URL, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
 	}
 
+	if !conn.IsConnected() {
+		logger.Warn("NATS not reachable, connecting in the background", zap.String("url", redactURL(cfg.URL)))
+		return conn, nil
+	}
+
 	logger.Info("Connected to NATS",
 		zap.String("url", redactURL(conn.ConnectedUrl())),
 		zap.String("server_id", conn.ConnectedServerId()),
@@ -61,6 +125,99 @@ func Connect(ctx context.Context, cfg ClientConfig, logger *zap.Logger) (*nats.C
 	return conn, nil
 }
 
+// StatusOptions returns options that log connection state changes like
+// Connect does and also report them as component status through host:
+// a recoverable error on disconnect or lame duck mode, OK on reconnect.
+// The initial connection is reported by StartWhenConnected.
+func StatusOptions(host component.Host, logger *zap.Logger) []nats.Option {
+	return []nats.Option{
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				logger.Warn("NATS disconnected", zap.Error(err))
+			}
+			componentstatus.ReportStatus(host, componentstatus.NewRecoverableErrorEvent(errors.Join(errDisconnected, err)))
+		}),
+		nats.ReconnectHandler(func(nc *nats.Conn) {
+			logger.Info("NATS reconnected", zap.String("url", redactURL(nc.ConnectedUrl())))
+			componentstatus.ReportStatus(host, componentstatus.NewEvent(componentstatus.StatusOK))
+		}),
+		nats.LameDuckModeHandler(func(nc *nats.Conn) {
+			logger.Warn("NATS server entered lame duck mode", zap.String("server_id", nc.ConnectedServerId()))
+			componentstatus.ReportStatus(host, componentstatus.NewRecoverableErrorEvent(errLameDuck))
+		}),
+	}
+}
+
+// StartWhenConnected runs start, which sets up what a component needs a
+// connection for, as soon as conn is connected. If it already is, start runs
+// right away and its error is returned. Otherwise a recoverable error is
+// reported through host and start runs in the background once conn
+// connects, reporting OK when it succeeds or a fatal error when it fails.
+// If conn is closed before it ever connects, because a finite max_reconnects
+// ran out, a permanent error is reported instead.
+// The returned function stops waiting and waits for a running start to
+// return; components call it first on shutdown.
+func StartWhenConnected(ctx context.Context, host component.Host, conn *nats.Conn, logger *zap.Logger, start func(context.Context) error) (func(), error) {
+	if conn.IsConnected() {
+		return func() {}, start(ctx)
+	}
+
+	logger.Warn("Deferring start until connected to NATS")
+	componentstatus.ReportStatus(host, componentstatus.NewRecoverableErrorEvent(ErrNotConnected))
+
+	bgCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := WaitConnected(bgCtx, conn); err != nil {
+			if errors.Is(err, nats.ErrConnectionClosed) {
+				logger.Error("NATS connection closed before connecting", zap.Error(err))
+				componentstatus.ReportStatus(host, componentstatus.NewPermanentErrorEvent(err))
+			}
+			return
+		}
+		logger.Info("Connected to NATS",
+			zap.String("url", redactURL(conn.ConnectedUrl())),
+			zap.String("server_id", conn.ConnectedServerId()),
+		)
+		if err := start(bgCtx); err != nil {
+			if bgCtx.Err() == nil {
+				logger.Error("Failed to start after connecting to NATS", zap.Error(err))
+				componentstatus.ReportStatus(host, componentstatus.NewFatalErrorEvent(err))
+			}
+			return
+		}
+		componentstatus.ReportStatus(host, componentstatus.NewEvent(componentstatus.StatusOK))
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}, nil
+}
+
+// WaitConnected blocks until conn is connected. It fails if conn is closed
+// first or ctx is done.
+func WaitConnected(ctx context.Context, conn *nats.Conn) error {
+	// Listen before checking so that no change is missed in between. The
+	// listener stays registered: RemoveStatusListener would also drop those
+	// of other components sharing conn.
+	ch := conn.StatusChanged(nats.CONNECTED, nats.CLOSED)
+	for {
+		switch {
+		case conn.IsConnected():
+			return nil
+		case conn.IsClosed():
+			return nats.ErrConnectionClosed
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 // redactURL removes credentials from a URL for safe logging.
 func redactURL(rawURL string) string {
 	u, err := url.Parse(rawURL)
@@ -0,0 +1,14 @@
+package nats
+
+// Dead letter headers. A receiver adds them to a message it republishes to
+// its dead letter subject, next to the original headers.
+const (
+	// HeaderDeadLetterError holds why the message was dead-lettered.
+	HeaderDeadLetterError = "Otel-Dead-Letter-Error"
+
+	// HeaderDeadLetterSubject holds the subject the message was received on.
+	HeaderDeadLetterSubject = "Otel-Dead-Letter-Subject"
+
+	// HeaderDeadLetterDeliveries holds the number of times the message was delivered.
+	HeaderDeadLetterDeliveries = "Otel-Dead-Letter-Deliveries"
+)
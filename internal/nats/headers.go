@@ -0,0 +1,45 @@
+package nats
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/mikluko/otelnats"
+)
+
+// headerNameRegex validates header names: printable ASCII without
+// whitespace or colons, as for HTTP field names.
+var headerNameRegex = regexp.MustCompile(`^[!-9;-~]+$`)
+
+// reservedHeaders are set by the exporter itself and cannot be configured.
+var reservedHeaders = []string{
+	otelnats.HeaderContentType,
+	otelnats.HeaderOtelSignal,
+	HeaderContentEncoding,
+	HeaderClaimCheckBucket,
+	HeaderClaimCheckObject,
+	HeaderExportError,
+	HeaderExportErrorPermanent,
+	HeaderDeadLetterError,
+	HeaderDeadLetterSubject,
+	HeaderDeadLetterDeliveries,
+}
+
+// ValidateHeaderName checks that name can be used for a user-defined header.
+// Protocol headers and the Nats- prefix used by the server (e.g. Nats-Msg-Id)
+// are reserved.
+func ValidateHeaderName(name string) error {
+	if !headerNameRegex.MatchString(name) {
+		return errors.New("header name contains invalid characters")
+	}
+	for _, reserved := range reservedHeaders {
+		if strings.EqualFold(name, reserved) {
+			return errors.New("header " + name + " is reserved")
+		}
+	}
+	if len(name) >= len("Nats-") && strings.EqualFold(name[:len("Nats-")], "Nats-") {
+		return errors.New("header " + name + " is reserved (Nats- prefix)")
+	}
+	return nil
+}
@@ -0,0 +1,335 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// Provisioning policies for JetStream streams and consumers.
+const (
+	// ProvisionCreateOnly creates the resource if it is missing and leaves an
+	// existing one untouched, logging any drift from the configuration.
+	ProvisionCreateOnly = "create_only"
+
+	// ProvisionReconcile creates the resource if it is missing and updates an
+	// existing one to match the configuration.
+	ProvisionReconcile = "reconcile"
+
+	// ProvisionVerifyOnly never mutates the cluster; a missing resource or any
+	// drift from the configuration is an error.
+	ProvisionVerifyOnly = "verify_only"
+)
+
+// ValidateProvisionPolicy checks that policy is a known provisioning policy.
+// An empty policy defaults to create_only.
+func ValidateProvisionPolicy(policy string) error {
+	switch policy {
+	case "", ProvisionCreateOnly, ProvisionReconcile, ProvisionVerifyOnly:
+		return nil
+	default:
+		return errors.New("policy must be create_only, reconcile or verify_only")
+	}
+}
+
+// ParseRetention maps a retention name onto the JetStream retention policy.
+// An empty name is limits.
+func ParseRetention(s string) (jetstream.RetentionPolicy, error) {
+	switch s {
+	case "", "limits":
+		return jetstream.LimitsPolicy, nil
+	case "interest":
+		return jetstream.InterestPolicy, nil
+	case "workqueue":
+		return jetstream.WorkQueuePolicy, nil
+	default:
+		return 0, errors.New("retention must be limits, interest or workqueue")
+	}
+}
+
+// ParseStorage maps a storage name onto the JetStream storage type.
+// An empty name is file.
+func ParseStorage(s string) (jetstream.StorageType, error) {
+	switch s {
+	case "", "file":
+		return jetstream.FileStorage, nil
+	case "memory":
+		return jetstream.MemoryStorage, nil
+	default:
+		return 0, errors.New("storage must be file or memory")
+	}
+}
+
+// ParseDeliverPolicy maps a deliver policy name onto the JetStream deliver
+// policy. An empty name is all.
+func ParseDeliverPolicy(s string) (jetstream.DeliverPolicy, error) {
+	switch s {
+	case "", "all":
+		return jetstream.DeliverAllPolicy, nil
+	case "last":
+		return jetstream.DeliverLastPolicy, nil
+	case "new":
+		return jetstream.DeliverNewPolicy, nil
+	case "last_per_subject":
+		return jetstream.DeliverLastPerSubjectPolicy, nil
+	case "by_start_sequence":
+		return jetstream.DeliverByStartSequencePolicy, nil
+	case "by_start_time":
+		return jetstream.DeliverByStartTimePolicy, nil
+	default:
+		return 0, errors.New("deliver_policy must be all, last, new, last_per_subject, by_start_sequence or by_start_time")
+	}
+}
+
+// ParseReplayPolicy maps a replay policy name onto the JetStream replay
+// policy. An empty name is instant.
+func ParseReplayPolicy(s string) (jetstream.ReplayPolicy, error) {
+	switch s {
+	case "", "instant":
+		return jetstream.ReplayInstantPolicy, nil
+	case "original":
+		return jetstream.ReplayOriginalPolicy, nil
+	default:
+		return 0, errors.New("replay_policy must be instant or original")
+	}
+}
+
+// ProvisionStream makes the stream named in desired match it according to policy.
+func ProvisionStream(ctx context.Context, js jetstream.JetStream, policy string, desired jetstream.StreamConfig, logger *zap.Logger) error {
+	stream, err := js.Stream(ctx, desired.Name)
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		if policy == ProvisionVerifyOnly {
+			return fmt.Errorf("stream %q does not exist", desired.Name)
+		}
+		if _, err := js.CreateStream(ctx, desired); err != nil {
+			return fmt.Errorf("failed to create stream %q: %w", desired.Name, err)
+		}
+		logger.Info("Created JetStream stream", zap.String("stream", desired.Name))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to look up stream %q: %w", desired.Name, err)
+	}
+
+	actual := stream.CachedInfo().Config
+	diff := streamDiff(actual, desired)
+	if len(diff) == 0 {
+		return nil
+	}
+
+	switch policy {
+	case ProvisionReconcile:
+		// The server rejects changes to the storage and to or from workqueue retention
+		if fixed := immutableStreamDiff(actual, desired); len(fixed) > 0 {
+			return fmt.Errorf("stream %q cannot be reconciled, its %s cannot be changed after creation: recreate the stream", desired.Name, strings.Join(fixed, ", "))
+		}
+		// Only update provisioned settings, keeping everything else as is
+		updated := actual
+		updated.Subjects = desired.Subjects
+		updated.Retention = desired.Retention
+		updated.MaxAge = desired.MaxAge
+		updated.MaxBytes = desired.MaxBytes
+		updated.Replicas = desired.Replicas
+		updated.Storage = desired.Storage
+		updated.MaxMsgsPerSubject = desired.MaxMsgsPerSubject
+		if _, err := js.UpdateStream(ctx, updated); err != nil {
+			return fmt.Errorf("failed to update stream %q (%s): %w", desired.Name, strings.Join(diff, ", "), err)
+		}
+		logger.Info("Updated JetStream stream",
+			zap.String("stream", desired.Name),
+			zap.Strings("changes", diff),
+		)
+		return nil
+	case ProvisionVerifyOnly:
+		return fmt.Errorf("stream %q does not match configuration: %s", desired.Name, strings.Join(diff, ", "))
+	default:
+		logger.Warn("JetStream stream does not match configuration",
+			zap.String("stream", desired.Name),
+			zap.Strings("differences", diff),
+		)
+		return nil
+	}
+}
+
+// ProvisionConsumer makes the durable consumer named in desired match it
+// according to policy, and returns it.
+func ProvisionConsumer(ctx context.Context, js jetstream.JetStream, stream, policy string, desired jetstream.ConsumerConfig, logger *zap.Logger) (jetstream.Consumer, error) {
+	consumer, err := js.Consumer(ctx, stream, desired.Durable)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) {
+		if policy == ProvisionVerifyOnly {
+			return nil, fmt.Errorf("consumer %q on stream %q does not exist", desired.Durable, stream)
+		}
+		consumer, err = js.CreateConsumer(ctx, stream, desired)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create consumer %q on stream %q: %w", desired.Durable, stream, err)
+		}
+		logger.Info("Created JetStream consumer",
+			zap.String("stream", stream),
+			zap.String("consumer", desired.Durable),
+		)
+		return consumer, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up consumer %q on stream %q: %w", desired.Durable, stream, err)
+	}
+
+	actual := consumer.CachedInfo().Config
+	diff := consumerDiff(actual, desired)
+	if len(diff) == 0 {
+		return consumer, nil
+	}
+
+	switch policy {
+	case ProvisionReconcile:
+		// The server rejects changes to the start position and replay policy
+		if fixed := immutableConsumerDiff(actual, desired); len(fixed) > 0 {
+			return nil, fmt.Errorf("consumer %q on stream %q cannot be reconciled, its %s cannot be changed after creation: use a new durable name", desired.Durable, stream, strings.Join(fixed, ", "))
+		}
+		// Only update provisioned settings, keeping everything else as is
+		updated := actual
+		updated.FilterSubject = ""
+		updated.FilterSubjects = desired.FilterSubjects
+		if desired.AckWait > 0 {
+			updated.AckWait = desired.AckWait
+		}
+		consumer, err = js.UpdateConsumer(ctx, stream, updated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update consumer %q on stream %q (%s): %w", desired.Durable, stream, strings.Join(diff, ", "), err)
+		}
+		logger.Info("Updated JetStream consumer",
+			zap.String("stream", stream),
+			zap.String("consumer", desired.Durable),
+			zap.Strings("changes", diff),
+		)
+		return consumer, nil
+	case ProvisionVerifyOnly:
+		return nil, fmt.Errorf("consumer %q on stream %q does not match configuration: %s", desired.Durable, stream, strings.Join(diff, ", "))
+	default:
+		logger.Warn("JetStream consumer does not match configuration",
+			zap.String("stream", stream),
+			zap.String("consumer", desired.Durable),
+			zap.Strings("differences", diff),
+		)
+		return consumer, nil
+	}
+}
+
+// streamDiff describes the provisioned settings in which actual differs from desired.
+func streamDiff(actual, desired jetstream.StreamConfig) []string {
+	var diff []string
+	if !sameSet(actual.Subjects, desired.Subjects) {
+		diff = append(diff, fmt.Sprintf("subjects %v != %v", actual.Subjects, desired.Subjects))
+	}
+	if actual.Retention != desired.Retention {
+		diff = append(diff, fmt.Sprintf("retention %s != %s", actual.Retention, desired.Retention))
+	}
+	if actual.MaxAge != desired.MaxAge {
+		diff = append(diff, fmt.Sprintf("max_age %s != %s", actual.MaxAge, desired.MaxAge))
+	}
+	if unlimited(actual.MaxBytes) != unlimited(desired.MaxBytes) {
+		diff = append(diff, fmt.Sprintf("max_bytes %d != %d", actual.MaxBytes, desired.MaxBytes))
+	}
+	if max(actual.Replicas, 1) != max(desired.Replicas, 1) {
+		diff = append(diff, fmt.Sprintf("replicas %d != %d", actual.Replicas, desired.Replicas))
+	}
+	if actual.Storage != desired.Storage {
+		diff = append(diff, fmt.Sprintf("storage %s != %s", actual.Storage, desired.Storage))
+	}
+	if unlimited(actual.MaxMsgsPerSubject) != unlimited(desired.MaxMsgsPerSubject) {
+		diff = append(diff, fmt.Sprintf("max_msgs_per_subject %d != %d", actual.MaxMsgsPerSubject, desired.MaxMsgsPerSubject))
+	}
+	return diff
+}
+
+// immutableStreamDiff is streamDiff for the settings the server does not
+// allow to change on an existing stream: the storage, and the retention
+// to or from workqueue.
+func immutableStreamDiff(actual, desired jetstream.StreamConfig) []string {
+	var diff []string
+	if actual.Retention != desired.Retention &&
+		(actual.Retention == jetstream.WorkQueuePolicy || desired.Retention == jetstream.WorkQueuePolicy) {
+		diff = append(diff, fmt.Sprintf("retention %s != %s", actual.Retention, desired.Retention))
+	}
+	if actual.Storage != desired.Storage {
+		diff = append(diff, fmt.Sprintf("storage %s != %s", actual.Storage, desired.Storage))
+	}
+	return diff
+}
+
+// consumerDiff describes the provisioned settings in which actual differs from desired.
+func consumerDiff(actual, desired jetstream.ConsumerConfig) []string {
+	diff := immutableConsumerDiff(actual, desired)
+	if !sameSet(filterSubjects(actual), filterSubjects(desired)) {
+		diff = append(diff, fmt.Sprintf("filter_subjects %v != %v", filterSubjects(actual), filterSubjects(desired)))
+	}
+	if desired.AckWait > 0 && actual.AckWait != desired.AckWait {
+		diff = append(diff, fmt.Sprintf("ack_wait %s != %s", actual.AckWait, desired.AckWait))
+	}
+	return diff
+}
+
+// immutableConsumerDiff is consumerDiff for the settings the server does not
+// allow to change on an existing consumer.
+func immutableConsumerDiff(actual, desired jetstream.ConsumerConfig) []string {
+	var diff []string
+	if actual.DeliverPolicy != desired.DeliverPolicy {
+		diff = append(diff, fmt.Sprintf("deliver_policy %s != %s", actual.DeliverPolicy, desired.DeliverPolicy))
+	}
+	if actual.OptStartSeq != desired.OptStartSeq {
+		diff = append(diff, fmt.Sprintf("opt_start_seq %d != %d", actual.OptStartSeq, desired.OptStartSeq))
+	}
+	if !sameTime(actual.OptStartTime, desired.OptStartTime) {
+		diff = append(diff, fmt.Sprintf("opt_start_time %s != %s", formatTime(actual.OptStartTime), formatTime(desired.OptStartTime)))
+	}
+	if actual.ReplayPolicy != desired.ReplayPolicy {
+		diff = append(diff, fmt.Sprintf("replay_policy %s != %s", actual.ReplayPolicy, desired.ReplayPolicy))
+	}
+	return diff
+}
+
+// filterSubjects returns the consumer's filters whether they are configured
+// as a single FilterSubject or as FilterSubjects.
+func filterSubjects(cfg jetstream.ConsumerConfig) []string {
+	if cfg.FilterSubject != "" {
+		return []string{cfg.FilterSubject}
+	}
+	return cfg.FilterSubjects
+}
+
+// sameTime reports whether a and b are both unset or the same instant.
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// formatTime renders an optional time for drift messages.
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "none"
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// unlimited normalizes the "no limit" values 0 and -1 to -1.
+func unlimited(n int64) int64 {
+	if n <= 0 {
+		return -1
+	}
+	return n
+}
+
+// sameSet reports whether a and b hold the same strings, ignoring order.
+func sameSet(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(a, b)
+}
@@ -0,0 +1,190 @@
+package nats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/mikluko/otelnats-collector/internal/testutil"
+)
+
+func newTestJetStream(t *testing.T) jetstream.JetStream {
+	t.Helper()
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(nc.Close)
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	return js
+}
+
+func TestProvisionStream(t *testing.T) {
+	ctx := context.Background()
+	desired := jetstream.StreamConfig{
+		Name:     "OTEL",
+		Subjects: []string{"otel.logs"},
+		MaxAge:   time.Hour,
+		Storage:  jetstream.MemoryStorage,
+	}
+
+	t.Run("verify_only missing", func(t *testing.T) {
+		js := newTestJetStream(t)
+		err := ProvisionStream(ctx, js, ProvisionVerifyOnly, desired, zap.NewNop())
+		require.ErrorContains(t, err, `stream "OTEL" does not exist`)
+		_, err = js.Stream(ctx, "OTEL")
+		assert.ErrorIs(t, err, jetstream.ErrStreamNotFound)
+	})
+
+	t.Run("create missing", func(t *testing.T) {
+		js := newTestJetStream(t)
+		require.NoError(t, ProvisionStream(ctx, js, ProvisionCreateOnly, desired, zap.NewNop()))
+		stream, err := js.Stream(ctx, "OTEL")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"otel.logs"}, stream.CachedInfo().Config.Subjects)
+		assert.Equal(t, time.Hour, stream.CachedInfo().Config.MaxAge)
+
+		// A matching stream passes verification
+		require.NoError(t, ProvisionStream(ctx, js, ProvisionVerifyOnly, desired, zap.NewNop()))
+	})
+
+	t.Run("drift", func(t *testing.T) {
+		js := newTestJetStream(t)
+		_, err := js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:     "OTEL",
+			Subjects: []string{"otel.logs"},
+			MaxAge:   time.Minute,
+			Storage:  jetstream.MemoryStorage,
+		})
+		require.NoError(t, err)
+
+		require.NoError(t, ProvisionStream(ctx, js, ProvisionCreateOnly, desired, zap.NewNop()))
+		stream, err := js.Stream(ctx, "OTEL")
+		require.NoError(t, err)
+		assert.Equal(t, time.Minute, stream.CachedInfo().Config.MaxAge, "create_only must not update")
+
+		err = ProvisionStream(ctx, js, ProvisionVerifyOnly, desired, zap.NewNop())
+		require.ErrorContains(t, err, "max_age 1m0s != 1h0m0s")
+
+		require.NoError(t, ProvisionStream(ctx, js, ProvisionReconcile, desired, zap.NewNop()))
+		stream, err = js.Stream(ctx, "OTEL")
+		require.NoError(t, err)
+		assert.Equal(t, time.Hour, stream.CachedInfo().Config.MaxAge)
+	})
+
+	t.Run("immutable drift", func(t *testing.T) {
+		js := newTestJetStream(t)
+		_, err := js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:      "OTEL",
+			Subjects:  []string{"otel.logs"},
+			MaxAge:    time.Minute,
+			Retention: jetstream.WorkQueuePolicy,
+			Storage:   jetstream.FileStorage,
+		})
+		require.NoError(t, err)
+
+		err = ProvisionStream(ctx, js, ProvisionReconcile, desired, zap.NewNop())
+		require.ErrorContains(t, err, `stream "OTEL" cannot be reconciled, its retention WorkQueue != Limits, storage File != Memory cannot be changed after creation: recreate the stream`)
+		stream, err := js.Stream(ctx, "OTEL")
+		require.NoError(t, err)
+		assert.Equal(t, time.Minute, stream.CachedInfo().Config.MaxAge, "nothing must be updated")
+	})
+}
+
+func TestProvisionConsumer(t *testing.T) {
+	ctx := context.Background()
+	desired := jetstream.ConsumerConfig{
+		Durable:        "otel",
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		AckWait:        10 * time.Second,
+		DeliverPolicy:  jetstream.DeliverNewPolicy,
+		FilterSubjects: []string{"otel.logs.>"},
+	}
+	newStream := func(t *testing.T) jetstream.JetStream {
+		js := newTestJetStream(t)
+		_, err := js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:     "OTEL",
+			Subjects: []string{"otel.>"},
+			Storage:  jetstream.MemoryStorage,
+		})
+		require.NoError(t, err)
+		return js
+	}
+
+	t.Run("verify_only missing", func(t *testing.T) {
+		js := newStream(t)
+		_, err := ProvisionConsumer(ctx, js, "OTEL", ProvisionVerifyOnly, desired, zap.NewNop())
+		require.ErrorContains(t, err, `consumer "otel" on stream "OTEL" does not exist`)
+	})
+
+	t.Run("create missing", func(t *testing.T) {
+		js := newStream(t)
+		consumer, err := ProvisionConsumer(ctx, js, "OTEL", ProvisionCreateOnly, desired, zap.NewNop())
+		require.NoError(t, err)
+		assert.Equal(t, "otel", consumer.CachedInfo().Name)
+		assert.Equal(t, jetstream.DeliverNewPolicy, consumer.CachedInfo().Config.DeliverPolicy)
+
+		_, err = ProvisionConsumer(ctx, js, "OTEL", ProvisionVerifyOnly, desired, zap.NewNop())
+		require.NoError(t, err)
+	})
+
+	t.Run("drift", func(t *testing.T) {
+		js := newStream(t)
+		_, err := js.CreateConsumer(ctx, "OTEL", jetstream.ConsumerConfig{
+			Durable:       "otel",
+			AckPolicy:     jetstream.AckExplicitPolicy,
+			AckWait:       10 * time.Second,
+			FilterSubject: "otel.traces",
+		})
+		require.NoError(t, err)
+
+		consumer, err := ProvisionConsumer(ctx, js, "OTEL", ProvisionCreateOnly, desired, zap.NewNop())
+		require.NoError(t, err)
+		assert.Equal(t, "otel.traces", consumer.CachedInfo().Config.FilterSubject, "create_only must not update")
+
+		_, err = ProvisionConsumer(ctx, js, "OTEL", ProvisionVerifyOnly, desired, zap.NewNop())
+		require.ErrorContains(t, err, "filter_subjects [otel.traces] != [otel.logs.>]")
+
+		// Deliver policy is immutable, so only reconcile mutable settings
+		mutable := desired
+		mutable.DeliverPolicy = jetstream.DeliverAllPolicy
+		consumer, err = ProvisionConsumer(ctx, js, "OTEL", ProvisionReconcile, mutable, zap.NewNop())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"otel.logs.>"}, consumer.CachedInfo().Config.FilterSubjects)
+	})
+
+	t.Run("start position drift", func(t *testing.T) {
+		js := newStream(t)
+		start := time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC)
+		byTime := desired
+		byTime.DeliverPolicy = jetstream.DeliverByStartTimePolicy
+		byTime.OptStartTime = &start
+		byTime.ReplayPolicy = jetstream.ReplayOriginalPolicy
+		_, err := ProvisionConsumer(ctx, js, "OTEL", ProvisionCreateOnly, byTime, zap.NewNop())
+		require.NoError(t, err)
+
+		_, err = ProvisionConsumer(ctx, js, "OTEL", ProvisionVerifyOnly, byTime, zap.NewNop())
+		require.NoError(t, err)
+
+		later := start.Add(time.Hour)
+		byTime.OptStartTime = &later
+		byTime.ReplayPolicy = jetstream.ReplayInstantPolicy
+		_, err = ProvisionConsumer(ctx, js, "OTEL", ProvisionVerifyOnly, byTime, zap.NewNop())
+		require.ErrorContains(t, err, "opt_start_time 2026-10-16T06:00:00Z != 2026-10-16T07:00:00Z, replay_policy original != instant")
+
+		// Reconcile cannot move the start position and leaves the consumer as is
+		byTime.AckWait = 20 * time.Second
+		_, err = ProvisionConsumer(ctx, js, "OTEL", ProvisionReconcile, byTime, zap.NewNop())
+		require.EqualError(t, err, `consumer "otel" on stream "OTEL" cannot be reconciled, its opt_start_time 2026-10-16T06:00:00Z != 2026-10-16T07:00:00Z, replay_policy original != instant cannot be changed after creation: use a new durable name`)
+		consumer, err := js.Consumer(ctx, "OTEL", "otel")
+		require.NoError(t, err)
+		assert.Equal(t, 10*time.Second, consumer.CachedInfo().Config.AckWait)
+		assert.Equal(t, start, *consumer.CachedInfo().Config.OptStartTime)
+	})
+}
@@ -0,0 +1,12 @@
+package nats
+
+// Request-reply headers. A receiver answers each request with an OTLP
+// Export*ServiceResponse in the encoding of the request; when the pipeline
+// failed to consume the batch, the reply carries these headers instead.
+const (
+	// HeaderExportError holds the error message of a failed export.
+	HeaderExportError = "Otel-Export-Error"
+
+	// HeaderExportErrorPermanent is "true" if retrying the export cannot succeed.
+	HeaderExportErrorPermanent = "Otel-Export-Error-Permanent"
+)
@@ -0,0 +1,29 @@
+package nats
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/mikluko/otelnats-collector/internal/metadata"
+)
+
+// RegisterConnectionTelemetry reports the reconnects and the pending buffered
+// bytes of conn through tb. Both are read from the client on each collection,
+// so they also cover connections shared through the nats extension.
+func RegisterConnectionTelemetry(tb *metadata.TelemetryBuilder, conn *nats.Conn) error {
+	if err := tb.RegisterNatsReconnectsCallback(func(_ context.Context, o metric.Int64Observer) error {
+		o.Observe(int64(conn.Stats().Reconnects))
+		return nil
+	}); err != nil {
+		return err
+	}
+	return tb.RegisterNatsPendingBytesCallback(func(_ context.Context, o metric.Int64Observer) error {
+		// Buffered fails once the connection is closed; nothing is pending then
+		if n, err := conn.Buffered(); err == nil {
+			o.Observe(int64(n))
+		}
+		return nil
+	})
+}
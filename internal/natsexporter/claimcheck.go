@@ -0,0 +1,93 @@
+package natsexporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nuid"
+	"go.uber.org/zap"
+
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+)
+
+// claimCheckPublisher uploads payloads larger than threshold to an Object
+// Store bucket and hands a reference message with an empty body to next.
+type claimCheckPublisher struct {
+	next      publisher
+	store     jetstream.ObjectStore
+	bucket    string
+	threshold int
+	logger    *zap.Logger
+}
+
+func (p *claimCheckPublisher) publish(ctx context.Context, msg *nats.Msg) error {
+	if len(msg.Data)+headerSize(msg.Header) <= p.threshold {
+		return p.next.publish(ctx, msg)
+	}
+
+	name := nuid.Next()
+	if _, err := p.store.PutBytes(ctx, name, msg.Data); err != nil {
+		return fmt.Errorf("failed to upload payload to object store %q: %w", p.bucket, err)
+	}
+
+	ref := &nats.Msg{
+		Subject: msg.Subject,
+		Header:  msg.Header,
+	}
+	ref.Header.Set(internalnats.HeaderClaimCheckBucket, p.bucket)
+	ref.Header.Set(internalnats.HeaderClaimCheckObject, name)
+
+	duplicate, err := p.publishReference(ctx, ref)
+	if err != nil {
+		// The reference was not delivered; the retry uploads a new object
+		p.delete(ctx, name)
+		return err
+	}
+	if duplicate {
+		// The stream kept the reference of an earlier attempt, to its own object
+		p.delete(ctx, name)
+		return nil
+	}
+
+	p.logger.Debug("published claim-check reference",
+		zap.String("subject", msg.Subject),
+		zap.String("bucket", p.bucket),
+		zap.String("object", name),
+		zap.Int("bytes", len(msg.Data)),
+	)
+	return nil
+}
+
+// publishReference hands ref to next and reports whether the stream dropped
+// it as a duplicate of a reference published by an earlier attempt.
+func (p *claimCheckPublisher) publishReference(ctx context.Context, ref *nats.Msg) (duplicate bool, err error) {
+	ap, ok := p.next.(ackPublisher)
+	if !ok {
+		return false, p.next.publish(ctx, ref)
+	}
+	ack, err := ap.publishAck(ctx, ref)
+	if err != nil {
+		return false, err
+	}
+	return ack != nil && ack.Duplicate, nil
+}
+
+// delete removes an uploaded object that no stored message references.
+func (p *claimCheckPublisher) delete(ctx context.Context, name string) {
+	if err := p.store.Delete(context.WithoutCancel(ctx), name); err != nil {
+		p.logger.Warn("failed to delete unreferenced object",
+			zap.String("bucket", p.bucket),
+			zap.String("object", name),
+			zap.Error(err),
+		)
+	}
+}
+
+func (p *claimCheckPublisher) flush(ctx context.Context) error {
+	if f, ok := p.next.(flusher); ok {
+		return f.flush(ctx)
+	}
+	return nil
+}
@@ -3,10 +3,15 @@ package natsexporter
 
 import (
 	"errors"
+	"strings"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configcompression"
+	"go.opentelemetry.io/collector/config/configoptional"
 	"go.opentelemetry.io/collector/config/configretry"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
+	"go.uber.org/zap"
 
 	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
 )
@@ -17,6 +22,24 @@ type Config struct {
 	configretry.BackOffConfig    `mapstructure:"retry_on_failure"`
 	internalnats.ClientConfig    `mapstructure:",squash"`
 
+	// QueueConfig buffers batches while NATS is unavailable, optionally on
+	// disk through a storage extension (sending_queue.storage). Each signal
+	// gets its own queue and num_consumers publishing goroutines.
+	QueueConfig configoptional.Optional[exporterhelper.QueueBatchConfig] `mapstructure:"sending_queue"`
+
+	// Connection references a nats extension (e.g. nats/main) whose
+	// connection is shared instead of opening a dedicated one.
+	// If set, the client settings above are ignored.
+	Connection *component.ID `mapstructure:"connection,omitempty"`
+
+	// Headers are static headers added to every message.
+	Headers map[string]string `mapstructure:"headers,omitempty"`
+
+	// FromClientMetadata lists client metadata keys (e.g. HTTP headers captured
+	// by a receiver with include_metadata) whose values are copied into message
+	// headers of the same name.
+	FromClientMetadata []string `mapstructure:"from_client_metadata,omitempty"`
+
 	// Traces configuration.
 	Traces SignalConfig `mapstructure:"traces"`
 
@@ -33,23 +56,223 @@ type SignalConfig struct {
 	// Supports template variables:
 	//   ${signal} - the signal type (traces, metrics, logs)
 	//   ${attr:key} - value of resource attribute "key"
+	// When resource attributes are referenced, each batch is split by resource
+	// and one message is published per distinct rendered subject. Attribute
+	// values are sanitized into valid subject tokens.
 	Subject string `mapstructure:"subject"`
 
+	// SubjectFallback is the token used in place of ${attr:key} when the
+	// resource attribute is missing or empty (default: unknown).
+	SubjectFallback string `mapstructure:"subject_fallback,omitempty"`
+
+	// Routes publish matching data to other subjects. Each span, data point
+	// or log record goes to the first route whose condition matches it, or
+	// to Subject if none does; a batch is published as one message per
+	// destination subject.
+	Routes []RouteConfig `mapstructure:"routes,omitempty"`
+
+	// Partitions publishes spans, or log records, to <subject>.<partition>
+	// with the partition derived from the trace ID, so that all data of a
+	// trace lands on the same subject. The partition is computed like the
+	// partition function of NATS subject mapping on the hex trace ID.
+	// Log records without a trace ID are spread across partitions.
+	// Not supported for metrics. Disabled if 0 (default).
+	Partitions int `mapstructure:"partitions,omitempty"`
+
+	// Snapshot publishes the latest data point of each series to its own
+	// subject, <subject>.<metric name>.<series hash>, instead of publishing
+	// batches. The series hash covers the resource and data point attributes.
+	// Bind the subjects to a stream keeping one message per subject to hold
+	// the current value of every series. Metrics only.
+	Snapshot bool `mapstructure:"snapshot,omitempty"`
+
 	// Encoding for message serialization (default: otlp_proto).
-	// Currently only otlp_proto is supported.
+	// Supported values:
+	//   otlp_proto - OTLP protobuf (Content-Type: application/x-protobuf)
+	//   otlp_json - OTLP JSON (Content-Type: application/json)
 	Encoding string `mapstructure:"encoding"`
+
+	// Compression applied to the marshalled payload: gzip, zstd, snappy or none (default).
+	// The algorithm is recorded in the Content-Encoding header so that
+	// receivers can decompress before unmarshalling.
+	Compression configcompression.Type `mapstructure:"compression,omitempty"`
+
+	// JetStream configuration for at-least-once delivery guarantees.
+	// If set, messages are published through JetStream and each batch is
+	// only reported as successful once the server has acknowledged it.
+	// If not set, uses core NATS (at-most-once delivery).
+	JetStream *JetStreamConfig `mapstructure:"jetstream,omitempty"`
+
+	// RequestReply sends each batch as a NATS request and waits for the
+	// receiver's OTLP export response, so a batch is only successful once a
+	// receiver has consumed it. Cannot be combined with JetStream.
+	RequestReply *RequestReplyConfig `mapstructure:"request_reply,omitempty"`
+
+	// ClaimCheck uploads oversized payloads to a JetStream Object Store bucket
+	// and publishes a reference message in their place.
+	// If set, payloads above the threshold are never split.
+	ClaimCheck *ClaimCheckConfig `mapstructure:"claim_check,omitempty"`
+
+	// RateLimit limits the messages and bytes per second published for the
+	// signal, protecting a shared NATS cluster from traffic spikes.
+	RateLimit *RateLimitConfig `mapstructure:"rate_limit,omitempty"`
+}
+
+// RateLimitConfig holds publish rate limiting configuration.
+// Both limits are token buckets holding one second worth of tokens.
+type RateLimitConfig struct {
+	// MessagesPerSecond is the maximum rate of published messages.
+	// A value of 0 disables the limit (default).
+	MessagesPerSecond float64 `mapstructure:"messages_per_second,omitempty"`
+
+	// BytesPerSecond is the maximum rate of published payload bytes.
+	// A message larger than the limit takes a whole second worth of tokens.
+	// A value of 0 disables the limit (default).
+	BytesPerSecond int `mapstructure:"bytes_per_second,omitempty"`
+
+	// Overflow is the policy for messages exceeding the limits (default: block):
+	//   block - wait for tokens, applying backpressure to the pipeline
+	//   reject - fail the export with a retryable error
+	//   shed - drop the records not matching Priority; the remaining
+	//          priority records wait for tokens
+	Overflow string `mapstructure:"overflow,omitempty"`
+
+	// Priority selects the records kept when shedding. If not set, shedding
+	// drops messages exceeding the limits entirely. Requires overflow: shed.
+	Priority *PriorityConfig `mapstructure:"priority,omitempty"`
+}
+
+// PriorityConfig selects priority records with an OTTL condition.
+type PriorityConfig struct {
+	// Context is the OTTL context the condition is evaluated in: resource
+	// (default), or span, datapoint or log for traces, metrics and logs.
+	Context string `mapstructure:"context,omitempty"`
+
+	// Condition is the OTTL condition matching priority records, e.g.
+	// severity_number >= SEVERITY_NUMBER_WARN.
+	Condition string `mapstructure:"condition"`
+}
+
+// RouteConfig routes data matching an OTTL condition to a subject.
+type RouteConfig struct {
+	// Context is the OTTL context the condition is evaluated in: resource
+	// (default), or span, datapoint or log for traces, metrics and logs.
+	Context string `mapstructure:"context,omitempty"`
+
+	// Condition is the OTTL condition, e.g. status.code == STATUS_CODE_ERROR.
+	Condition string `mapstructure:"condition"`
+
+	// Subject is the subject template matching data is published to.
+	// It supports the same variables as the signal subject.
+	Subject string `mapstructure:"subject"`
+}
+
+// JetStreamConfig holds JetStream-specific exporter configuration.
+type JetStreamConfig struct {
+	// Stream is the name of the stream expected to store published messages.
+	// If set, the server rejects messages that would land in a different stream.
+	// If empty, any stream bound to the subject is accepted.
+	Stream string `mapstructure:"stream,omitempty"`
+
+	// AckTimeout is the maximum time to wait for a PubAck from the server.
+	// Default is 5 seconds.
+	AckTimeout time.Duration `mapstructure:"ack_timeout,omitempty"`
+
+	// MaxPending enables asynchronous publishing with up to MaxPending
+	// unacknowledged messages in flight. Each export call still returns only
+	// once its own PubAck (or error) arrives, so retries keep working.
+	// A value of 0 publishes synchronously, one PubAck round-trip per batch (default).
+	MaxPending int `mapstructure:"max_pending,omitempty"`
+
+	// MsgID sets a Nats-Msg-Id header so that the stream's duplicate window
+	// drops batches stored twice when a PubAck is lost and the batch retried.
+	// Supported values:
+	//   content_hash - SHA-256 of the subject and payload
+	//   attr:<key> - subject and value of resource attribute <key>, falling
+	//                back to content_hash if a resource lacks it
+	// Disabled if empty (default).
+	MsgID string `mapstructure:"msg_id,omitempty"`
+
+	// Provision creates or verifies the stream at start.
+	// Requires Stream to be set.
+	Provision *StreamProvisionConfig `mapstructure:"provision,omitempty"`
+}
+
+// RequestReplyConfig holds request-reply delivery configuration.
+type RequestReplyConfig struct {
+	// Timeout is how long to wait for the reply (default: 5s).
+	// It must cover the receiving pipeline's processing time.
+	Timeout time.Duration `mapstructure:"timeout,omitempty"`
+}
+
+// StreamProvisionConfig describes the stream to provision.
+type StreamProvisionConfig struct {
+	// Policy controls how an existing stream is treated (default: create_only):
+	//   create_only - create if missing, log drift of an existing stream
+	//   reconcile - create if missing, update an existing stream to match
+	//   verify_only - never mutate; a missing stream or any drift fails start
+	Policy string `mapstructure:"policy,omitempty"`
+
+	// Subjects bound to the stream. Defaults to the signal subject, which
+	// must not reference resource attributes in that case.
+	Subjects []string `mapstructure:"subjects,omitempty"`
+
+	// Retention policy: limits (default), interest or workqueue.
+	Retention string `mapstructure:"retention,omitempty"`
+
+	// MaxAge is the maximum age of stored messages. 0 means unlimited.
+	MaxAge time.Duration `mapstructure:"max_age,omitempty"`
+
+	// MaxBytes is the maximum size of the stream. 0 means unlimited.
+	MaxBytes int64 `mapstructure:"max_bytes,omitempty"`
+
+	// Replicas is the number of stream replicas (default: 1).
+	Replicas int `mapstructure:"replicas,omitempty"`
+
+	// Storage type: file (default) or memory.
+	Storage string `mapstructure:"storage,omitempty"`
+
+	// MaxMsgsPerSubject is the number of messages kept per subject.
+	// 0 means unlimited, or 1 for a metrics snapshot.
+	MaxMsgsPerSubject int64 `mapstructure:"max_msgs_per_subject,omitempty"`
+}
+
+// ClaimCheckConfig holds claim-check configuration.
+type ClaimCheckConfig struct {
+	// Bucket is the Object Store bucket payloads are uploaded to.
+	// The bucket must exist; configure a TTL on it to expire objects
+	// that are never consumed.
+	Bucket string `mapstructure:"bucket"`
+
+	// Threshold is the message size in bytes above which the payload is
+	// uploaded. Default (0) is the server's max_payload, so only payloads
+	// that do not fit in a message are uploaded.
+	Threshold int `mapstructure:"threshold,omitempty"`
 }
 
 var _ component.Config = (*Config)(nil)
 
 // Validate checks if the configuration is valid.
 func (c *Config) Validate() error {
-	if err := internalnats.ValidateURL(c.ClientConfig.URL); err != nil {
-		return err
+	// Client settings only apply to a dedicated connection
+	if c.Connection == nil {
+		if err := internalnats.ValidateURL(c.ClientConfig.URL); err != nil {
+			return err
+		}
+		if err := c.ClientConfig.Auth.Validate(); err != nil {
+			return err
+		}
 	}
 
-	if err := c.ClientConfig.Auth.Validate(); err != nil {
-		return err
+	for name := range c.Headers {
+		if err := internalnats.ValidateHeaderName(name); err != nil {
+			return errors.New("headers: " + err.Error())
+		}
+	}
+	for _, key := range c.FromClientMetadata {
+		if err := internalnats.ValidateHeaderName(key); err != nil {
+			return errors.New("from_client_metadata: " + err.Error())
+		}
 	}
 
 	if c.Traces.Subject == "" && c.Metrics.Subject == "" && c.Logs.Subject == "" {
@@ -64,18 +287,195 @@ func (c *Config) Validate() error {
 	}
 
 	for name, cfg := range signals {
-		// Validate subject format if configured (no wildcards allowed for publishing)
+		// Validate subject template if configured (no wildcards allowed for publishing)
 		if cfg.Subject != "" {
-			if err := internalnats.ValidatePublishSubject(cfg.Subject); err != nil {
+			if err := validateSubjectTemplate(cfg.Subject, cfg.SubjectFallback, name); err != nil {
 				return errors.New(name + ".subject: " + err.Error())
 			}
 		}
 
+		if len(cfg.Routes) > 0 {
+			if cfg.Subject == "" {
+				return errors.New(name + ".routes requires subject to be configured")
+			}
+			if err := validateRoutes(cfg, name); err != nil {
+				return errors.New(name + "." + err.Error())
+			}
+		}
+
+		if cfg.Partitions < 0 {
+			return errors.New(name + ".partitions must be non-negative")
+		}
+		if cfg.Partitions > 0 && name == "metrics" {
+			return errors.New("metrics.partitions is not supported")
+		}
+
+		if cfg.Snapshot {
+			if err := validateSnapshot(cfg, name); err != nil {
+				return errors.New(name + ".snapshot: " + err.Error())
+			}
+		}
+
 		// Validate encoding if specified
-		if cfg.Encoding != "" && cfg.Encoding != defaultEncoding {
-			return errors.New("only otlp_proto encoding is currently supported")
+		if cfg.Encoding != "" && cfg.Encoding != encodingProto && cfg.Encoding != encodingJSON {
+			return errors.New(name + ".encoding must be otlp_proto or otlp_json")
+		}
+
+		if err := internalnats.ValidateCompression(cfg.Compression); err != nil {
+			return errors.New(name + ".compression: " + err.Error())
+		}
+
+		// Validate JetStream configuration if enabled for this signal
+		if cfg.JetStream != nil {
+			if cfg.JetStream.AckTimeout < 0 {
+				return errors.New(name + ".jetstream.ack_timeout must be non-negative")
+			}
+			if cfg.JetStream.MaxPending < 0 {
+				return errors.New(name + ".jetstream.max_pending must be non-negative")
+			}
+			if m := cfg.JetStream.MsgID; m != "" && m != msgIDContentHash &&
+				(!strings.HasPrefix(m, msgIDAttrPrefix) || len(m) == len(msgIDAttrPrefix)) {
+				return errors.New(name + ".jetstream.msg_id must be content_hash or attr:<key>")
+			}
+			if p := cfg.JetStream.Provision; p != nil {
+				if err := validateStreamProvision(cfg, name); err != nil {
+					return errors.New(name + ".jetstream.provision: " + err.Error())
+				}
+			}
+		}
+
+		// Validate request-reply configuration if enabled for this signal
+		if cfg.RequestReply != nil {
+			if cfg.JetStream != nil {
+				return errors.New(name + ".request_reply and jetstream are mutually exclusive")
+			}
+			if cfg.RequestReply.Timeout < 0 {
+				return errors.New(name + ".request_reply.timeout must be non-negative")
+			}
+		}
+
+		// Validate rate limiting configuration if enabled for this signal
+		if cfg.RateLimit != nil {
+			if err := validateRateLimit(cfg.RateLimit, name); err != nil {
+				return errors.New(name + ".rate_limit." + err.Error())
+			}
+		}
+
+		// Validate claim-check configuration if enabled for this signal
+		if cfg.ClaimCheck != nil {
+			if cfg.ClaimCheck.Bucket == "" {
+				return errors.New(name + ".claim_check.bucket is required when claim_check is enabled")
+			}
+			if !tokenRegex.MatchString(cfg.ClaimCheck.Bucket) {
+				return errors.New(name + ".claim_check.bucket contains invalid characters")
+			}
+			if cfg.ClaimCheck.Threshold < 0 {
+				return errors.New(name + ".claim_check.threshold must be non-negative")
+			}
 		}
 	}
 
 	return nil
 }
+
+// validateStreamProvision checks the stream provisioning settings of a signal.
+func validateStreamProvision(cfg SignalConfig, name string) error {
+	p := cfg.JetStream.Provision
+	if cfg.JetStream.Stream == "" {
+		return errors.New("stream is required")
+	}
+	if err := internalnats.ValidateProvisionPolicy(p.Policy); err != nil {
+		return err
+	}
+	if len(p.Subjects) == 0 {
+		t, err := parseSubjectTemplate(cfg.Subject, cfg.SubjectFallback, name)
+		if err == nil && t.dynamic {
+			return errors.New("subjects is required when subject references resource attributes")
+		}
+	}
+	for _, subject := range p.Subjects {
+		if err := internalnats.ValidateSubject(subject); err != nil {
+			return errors.New("subjects: " + err.Error())
+		}
+	}
+	if _, err := internalnats.ParseRetention(p.Retention); err != nil {
+		return err
+	}
+	if _, err := internalnats.ParseStorage(p.Storage); err != nil {
+		return err
+	}
+	if p.MaxAge < 0 || p.MaxBytes < 0 || p.Replicas < 0 || p.MaxMsgsPerSubject < 0 {
+		return errors.New("max_age, max_bytes, replicas and max_msgs_per_subject must be non-negative")
+	}
+	return nil
+}
+
+// validateSnapshot checks that snapshot mode is enabled on a static metrics
+// subject and not combined with other ways of choosing subjects.
+func validateSnapshot(cfg SignalConfig, name string) error {
+	if name != "metrics" {
+		return errors.New("only supported for metrics")
+	}
+	if t, err := parseSubjectTemplate(cfg.Subject, cfg.SubjectFallback, name); err == nil && t.dynamic {
+		return errors.New("subject must not reference resource attributes")
+	}
+	if len(cfg.Routes) > 0 {
+		return errors.New("cannot be combined with routes")
+	}
+	return nil
+}
+
+// validateRateLimit checks the rate limiting settings of a signal and parses
+// its priority condition.
+func validateRateLimit(rl *RateLimitConfig, name string) error {
+	if rl.MessagesPerSecond < 0 || rl.BytesPerSecond < 0 {
+		return errors.New("messages_per_second and bytes_per_second must be non-negative")
+	}
+	if rl.MessagesPerSecond == 0 && rl.BytesPerSecond == 0 {
+		return errors.New("messages_per_second or bytes_per_second is required")
+	}
+	switch rl.Overflow {
+	case "", overflowBlock, overflowReject, overflowShed:
+	default:
+		return errors.New("overflow must be block, reject or shed")
+	}
+	if rl.Priority == nil {
+		return nil
+	}
+	if rl.Overflow != overflowShed {
+		return errors.New("priority requires overflow: shed")
+	}
+	if rl.Priority.Condition == "" {
+		return errors.New("priority.condition is required")
+	}
+
+	set := component.TelemetrySettings{Logger: zap.NewNop()}
+	var err error
+	switch name {
+	case "traces":
+		_, err = newTracesPriority(rl.Priority, set)
+	case "metrics":
+		_, err = newMetricsPriority(rl.Priority, set)
+	default:
+		_, err = newLogsPriority(rl.Priority, set)
+	}
+	if err != nil {
+		return errors.New("priority." + err.Error())
+	}
+	return nil
+}
+
+// validateRoutes parses the routes of a signal to check their conditions.
+func validateRoutes(cfg SignalConfig, name string) error {
+	set := component.TelemetrySettings{Logger: zap.NewNop()}
+	var err error
+	switch name {
+	case "traces":
+		_, err = newTracesRouter(cfg, nil, set)
+	case "metrics":
+		_, err = newMetricsRouter(cfg, nil, set)
+	default:
+		_, err = newLogsRouter(cfg, nil, set)
+	}
+	return err
+}
@@ -2,9 +2,11 @@ package natsexporter
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
 
 	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
 )
@@ -64,7 +66,20 @@ func TestConfig_Validate(t *testing.T) {
 					Encoding: "json",
 				},
 			},
-			wantErr: "only otlp_proto encoding is currently supported",
+			wantErr: "traces.encoding must be otlp_proto or otlp_json",
+		},
+		{
+			name: "valid with otlp_json encoding",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{
+					Subject:  "otel.traces",
+					Encoding: "otlp_json",
+				},
+			},
+			wantErr: "",
 		},
 		{
 			name: "valid with explicit otlp_proto encoding",
@@ -79,6 +94,538 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: "",
 		},
+		{
+			name: "valid jetstream config",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{
+					Subject: "otel.traces",
+					JetStream: &JetStreamConfig{
+						Stream:     "OTEL",
+						AckTimeout: 10 * time.Second,
+					},
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name: "jetstream negative ack_timeout",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{
+					Subject: "otel.traces",
+					JetStream: &JetStreamConfig{
+						AckTimeout: -1,
+					},
+				},
+			},
+			wantErr: "traces.jetstream.ack_timeout must be non-negative",
+		},
+		{
+			name: "jetstream negative max_pending",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject: "otel.logs",
+					JetStream: &JetStreamConfig{
+						MaxPending: -1,
+					},
+				},
+			},
+			wantErr: "logs.jetstream.max_pending must be non-negative",
+		},
+		{
+			name: "valid subject template",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:         "otel.${signal}.${attr:k8s.cluster.name}.${attr:k8s.namespace.name}",
+					SubjectFallback: "none",
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name: "unknown template variable",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{Subject: "otel.${tenant}"},
+			},
+			wantErr: `logs.subject: unknown template variable "tenant"`,
+		},
+		{
+			name: "unterminated template variable",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{Subject: "otel.${attr:k8s.cluster.name"},
+			},
+			wantErr: "logs.subject: unterminated template variable",
+		},
+		{
+			name: "invalid subject fallback",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:         "otel.logs.${attr:k8s.cluster.name}",
+					SubjectFallback: "not.a.token",
+				},
+			},
+			wantErr: "subject_fallback contains invalid characters",
+		},
+		{
+			name: "wildcard in subject",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{Subject: "otel.logs.>"},
+			},
+			wantErr: "publish subject cannot contain wildcards",
+		},
+		{
+			name: "valid compression",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces:  SignalConfig{Subject: "otel.traces", Compression: "zstd"},
+				Metrics: SignalConfig{Subject: "otel.metrics", Compression: "gzip"},
+				Logs:    SignalConfig{Subject: "otel.logs", Compression: "snappy"},
+			},
+			wantErr: "",
+		},
+		{
+			name: "unsupported compression",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{Subject: "otel.traces", Compression: "lz4"},
+			},
+			wantErr: `traces.compression: unsupported compression "lz4"`,
+		},
+		{
+			name: "claim_check without bucket",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:    "otel.logs",
+					ClaimCheck: &ClaimCheckConfig{},
+				},
+			},
+			wantErr: "logs.claim_check.bucket is required when claim_check is enabled",
+		},
+		{
+			name: "claim_check invalid bucket",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:    "otel.logs",
+					ClaimCheck: &ClaimCheckConfig{Bucket: "otel.payloads"},
+				},
+			},
+			wantErr: "logs.claim_check.bucket contains invalid characters",
+		},
+		{
+			name: "claim_check negative threshold",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:    "otel.logs",
+					ClaimCheck: &ClaimCheckConfig{Bucket: "otel-payloads", Threshold: -1},
+				},
+			},
+			wantErr: "logs.claim_check.threshold must be non-negative",
+		},
+		{
+			name: "shared connection ignores client settings",
+			cfg: &Config{
+				Connection: func() *component.ID {
+					id := component.MustNewIDWithName("nats", "main")
+					return &id
+				}(),
+				Traces: SignalConfig{Subject: "otel.traces"},
+			},
+			wantErr: "",
+		},
+		{
+			name: "jetstream msg_id",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{Subject: "otel.traces", JetStream: &JetStreamConfig{MsgID: "content_hash"}},
+				Logs:   SignalConfig{Subject: "otel.logs", JetStream: &JetStreamConfig{MsgID: "attr:batch.id"}},
+			},
+			wantErr: "",
+		},
+		{
+			name: "jetstream invalid msg_id",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{Subject: "otel.logs", JetStream: &JetStreamConfig{MsgID: "attr:"}},
+			},
+			wantErr: "logs.jetstream.msg_id must be content_hash or attr:<key>",
+		},
+		{
+			name: "headers and from_client_metadata",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Headers:            map[string]string{"X-Cluster": "edge-1"},
+				FromClientMetadata: []string{"X-Tenant-Id"},
+				Logs:               SignalConfig{Subject: "otel.logs"},
+			},
+			wantErr: "",
+		},
+		{
+			name: "reserved header",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Headers: map[string]string{"content-type": "text/plain"},
+				Logs:    SignalConfig{Subject: "otel.logs"},
+			},
+			wantErr: "headers: header content-type is reserved",
+		},
+		{
+			name: "reserved header prefix",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				FromClientMetadata: []string{"Nats-Msg-Id"},
+				Logs:               SignalConfig{Subject: "otel.logs"},
+			},
+			wantErr: "from_client_metadata: header Nats-Msg-Id is reserved (Nats- prefix)",
+		},
+		{
+			name: "invalid header name",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Headers: map[string]string{"X Tenant": "acme"},
+				Logs:    SignalConfig{Subject: "otel.logs"},
+			},
+			wantErr: "headers: header name contains invalid characters",
+		},
+		{
+			name: "request_reply with jetstream",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:      "otel.logs",
+					RequestReply: &RequestReplyConfig{},
+					JetStream:    &JetStreamConfig{},
+				},
+			},
+			wantErr: "logs.request_reply and jetstream are mutually exclusive",
+		},
+		{
+			name: "request_reply negative timeout",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:      "otel.logs",
+					RequestReply: &RequestReplyConfig{Timeout: -1},
+				},
+			},
+			wantErr: "logs.request_reply.timeout must be non-negative",
+		},
+		{
+			name: "partitions",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{Subject: "otel.traces", Partitions: 8},
+				Logs:   SignalConfig{Subject: "otel.logs", Partitions: 8},
+			},
+			wantErr: "",
+		},
+		{
+			name: "partitions for metrics",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Metrics: SignalConfig{Subject: "otel.metrics", Partitions: 8},
+			},
+			wantErr: "metrics.partitions is not supported",
+		},
+		{
+			name: "negative partitions",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{Subject: "otel.traces", Partitions: -1},
+			},
+			wantErr: "traces.partitions must be non-negative",
+		},
+		{
+			name: "snapshot",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Metrics: SignalConfig{Subject: "otel.snapshot", Snapshot: true},
+			},
+			wantErr: "",
+		},
+		{
+			name: "snapshot for traces",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{Subject: "otel.traces", Snapshot: true},
+			},
+			wantErr: "traces.snapshot: only supported for metrics",
+		},
+		{
+			name: "snapshot with attribute subject",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Metrics: SignalConfig{Subject: "otel.snapshot.${attr:service.name}", Snapshot: true},
+			},
+			wantErr: "metrics.snapshot: subject must not reference resource attributes",
+		},
+		{
+			name: "routes",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{
+					Subject: "otel.traces",
+					Routes: []RouteConfig{
+						{Context: "span", Condition: "status.code == STATUS_CODE_ERROR", Subject: "otel.traces.errors"},
+						{Condition: `attributes["service.name"] == "checkout"`, Subject: "otel.traces.${attr:service.name}"},
+					},
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name: "routes invalid context",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Metrics: SignalConfig{
+					Subject: "otel.metrics",
+					Routes:  []RouteConfig{{Context: "log", Condition: "true", Subject: "otel.metrics.all"}},
+				},
+			},
+			wantErr: "metrics.routes[0].context must be resource or datapoint",
+		},
+		{
+			name: "routes invalid condition",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject: "otel.logs",
+					Routes:  []RouteConfig{{Context: "log", Condition: "severity_number >=", Subject: "otel.logs.errors"}},
+				},
+			},
+			wantErr: "logs.routes[0].condition: ",
+		},
+		{
+			name: "routes without subject",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Metrics: SignalConfig{Subject: "otel.metrics"},
+				Logs: SignalConfig{
+					Routes: []RouteConfig{{Condition: "true", Subject: "otel.logs.all"}},
+				},
+			},
+			wantErr: "logs.routes requires subject to be configured",
+		},
+		{
+			name: "jetstream provision",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject: "otel.logs",
+					JetStream: &JetStreamConfig{
+						Stream: "OTEL_LOGS",
+						Provision: &StreamProvisionConfig{
+							Policy:    "reconcile",
+							Retention: "interest",
+							MaxAge:    24 * time.Hour,
+							Storage:   "memory",
+						},
+					},
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name: "jetstream provision without stream",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs",
+					JetStream: &JetStreamConfig{Provision: &StreamProvisionConfig{}},
+				},
+			},
+			wantErr: "logs.jetstream.provision: stream is required",
+		},
+		{
+			name: "jetstream provision invalid policy",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs",
+					JetStream: &JetStreamConfig{Stream: "OTEL", Provision: &StreamProvisionConfig{Policy: "force"}},
+				},
+			},
+			wantErr: "logs.jetstream.provision: policy must be create_only, reconcile or verify_only",
+		},
+		{
+			name: "jetstream provision invalid retention",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs",
+					JetStream: &JetStreamConfig{Stream: "OTEL", Provision: &StreamProvisionConfig{Retention: "forever"}},
+				},
+			},
+			wantErr: "logs.jetstream.provision: retention must be limits, interest or workqueue",
+		},
+		{
+			name: "jetstream provision dynamic subject without subjects",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs.${attr:service.name}",
+					JetStream: &JetStreamConfig{Stream: "OTEL", Provision: &StreamProvisionConfig{}},
+				},
+			},
+			wantErr: "logs.jetstream.provision: subjects is required when subject references resource attributes",
+		},
+		{
+			name: "rate limit shed with priority",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject: "otel.logs",
+					RateLimit: &RateLimitConfig{
+						BytesPerSecond: 1 << 20,
+						Overflow:       "shed",
+						Priority:       &PriorityConfig{Context: "log", Condition: "severity_number >= SEVERITY_NUMBER_WARN"},
+					},
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name: "rate limit without limits",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{
+					Subject:   "otel.traces",
+					RateLimit: &RateLimitConfig{Overflow: "reject"},
+				},
+			},
+			wantErr: "traces.rate_limit.messages_per_second or bytes_per_second is required",
+		},
+		{
+			name: "rate limit invalid overflow",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{
+					Subject:   "otel.traces",
+					RateLimit: &RateLimitConfig{MessagesPerSecond: 100, Overflow: "drop"},
+				},
+			},
+			wantErr: "traces.rate_limit.overflow must be block, reject or shed",
+		},
+		{
+			name: "rate limit priority without shed",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject: "otel.logs",
+					RateLimit: &RateLimitConfig{
+						MessagesPerSecond: 100,
+						Priority:          &PriorityConfig{Condition: "true"},
+					},
+				},
+			},
+			wantErr: "logs.rate_limit.priority requires overflow: shed",
+		},
+		{
+			name: "rate limit priority invalid context",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Metrics: SignalConfig{
+					Subject: "otel.metrics",
+					RateLimit: &RateLimitConfig{
+						MessagesPerSecond: 100,
+						Overflow:          "shed",
+						Priority:          &PriorityConfig{Context: "span", Condition: "true"},
+					},
+				},
+			},
+			wantErr: "metrics.rate_limit.priority.context must be resource or datapoint",
+		},
 	}
 
 	for _, tt := range tests {
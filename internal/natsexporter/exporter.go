@@ -2,17 +2,31 @@ package natsexporter
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
 
 	"github.com/mikluko/otelnats"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottldatapoint"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottllog"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlspan"
+	"go.opentelemetry.io/collector/client"
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configcompression"
 	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"go.uber.org/zap"
 
+	"github.com/mikluko/otelnats-collector/internal/metadata"
 	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
 )
 
@@ -21,12 +35,63 @@ type natsExporter struct {
 	settings exporter.Settings
 	logger   *zap.Logger
 
+	// telemetry records the exporter's internal metrics, created on start
+	telemetry *metadata.TelemetryBuilder
+
 	conn *nats.Conn
+	js   jetstream.JetStream
+
+	// ownsConn is false when conn is shared through the nats extension
+	ownsConn bool
+
+	// maxPayload is the server's max_payload, read after connecting.
+	// Larger messages are split before publishing.
+	maxPayload int64
+
+	// Per-signal parsed subject templates
+	tracesSubject  *subjectTemplate
+	metricsSubject *subjectTemplate
+	logsSubject    *subjectTemplate
+
+	// Per-signal routers, nil if no routes are configured
+	tracesRouter  *router[*ottlspan.TransformContext]
+	metricsRouter *router[*ottldatapoint.TransformContext]
+	logsRouter    *router[*ottllog.TransformContext]
+
+	// Per-signal rate limiters, nil if not configured
+	tracesLimiter  *rateLimiter[ptrace.Traces]
+	metricsLimiter *rateLimiter[pmetric.Metrics]
+	logsLimiter    *rateLimiter[plog.Logs]
+
+	// Per-signal publishers (core NATS or JetStream)
+	tracesPublisher  publisher
+	metricsPublisher publisher
+	logsPublisher    publisher
 
-	// Proto marshalers for converting pdata to OTLP protobuf
+	// Marshalers for converting pdata to OTLP protobuf or JSON
 	tracesMarshaler  ptrace.Marshaler
 	metricsMarshaler pmetric.Marshaler
 	logsMarshaler    plog.Marshaler
+
+	// Per-signal encodings, used for the Content-Type header
+	tracesEncoding  otelnats.Encoding
+	metricsEncoding otelnats.Encoding
+	logsEncoding    otelnats.Encoding
+
+	// Per-signal Nats-Msg-Id generators, nil if disabled
+	tracesMsgID  *msgIDGenerator
+	metricsMsgID *msgIDGenerator
+	logsMsgID    *msgIDGenerator
+
+	// untracedLogs rotates the partition of log records without a trace ID
+	untracedLogs atomic.Uint64
+
+	// started is set once the connection is established and the publishers
+	// are set up; until then exports fail with a retryable error
+	started atomic.Bool
+
+	// stopDeferred stops a start deferred until NATS is reachable
+	stopDeferred func()
 }
 
 func newNatsExporter(cfg *Config, set exporter.Settings) *natsExporter {
@@ -37,42 +102,602 @@ func newNatsExporter(cfg *Config, set exporter.Settings) *natsExporter {
 	}
 }
 
-func (e *natsExporter) start(ctx context.Context, _ component.Host) error {
-	conn, err := internalnats.Connect(ctx, e.config.ClientConfig, e.logger)
+func (e *natsExporter) start(ctx context.Context, host component.Host) error {
+	var err error
+
+	// Parse per-signal subject templates
+	if e.tracesSubject, err = parseSubjectTemplate(e.config.Traces.Subject, e.config.Traces.SubjectFallback, otelnats.SignalTraces); err != nil {
+		return fmt.Errorf("traces.subject: %w", err)
+	}
+	if e.metricsSubject, err = parseSubjectTemplate(e.config.Metrics.Subject, e.config.Metrics.SubjectFallback, otelnats.SignalMetrics); err != nil {
+		return fmt.Errorf("metrics.subject: %w", err)
+	}
+	if e.logsSubject, err = parseSubjectTemplate(e.config.Logs.Subject, e.config.Logs.SubjectFallback, otelnats.SignalLogs); err != nil {
+		return fmt.Errorf("logs.subject: %w", err)
+	}
+
+	// Parse per-signal routes
+	set := e.settings.TelemetrySettings
+	if len(e.config.Traces.Routes) > 0 {
+		if e.tracesRouter, err = newTracesRouter(e.config.Traces, e.tracesSubject, set); err != nil {
+			return fmt.Errorf("traces.%w", err)
+		}
+	}
+	if len(e.config.Metrics.Routes) > 0 {
+		if e.metricsRouter, err = newMetricsRouter(e.config.Metrics, e.metricsSubject, set); err != nil {
+			return fmt.Errorf("metrics.%w", err)
+		}
+	}
+	if len(e.config.Logs.Routes) > 0 {
+		if e.logsRouter, err = newLogsRouter(e.config.Logs, e.logsSubject, set); err != nil {
+			return fmt.Errorf("logs.%w", err)
+		}
+	}
+
+	if e.telemetry, err = metadata.NewTelemetryBuilder(set); err != nil {
+		return err
+	}
+
+	// Initialize per-signal rate limiters
+	if rl := e.config.Traces.RateLimit; rl != nil {
+		if e.tracesLimiter, err = newTracesRateLimiter(rl, set, e.telemetry); err != nil {
+			return fmt.Errorf("traces.rate_limit.priority.%w", err)
+		}
+	}
+	if rl := e.config.Metrics.RateLimit; rl != nil {
+		if e.metricsLimiter, err = newMetricsRateLimiter(rl, set, e.telemetry); err != nil {
+			return fmt.Errorf("metrics.rate_limit.priority.%w", err)
+		}
+	}
+	if rl := e.config.Logs.RateLimit; rl != nil {
+		if e.logsLimiter, err = newLogsRateLimiter(rl, set, e.telemetry); err != nil {
+			return fmt.Errorf("logs.rate_limit.priority.%w", err)
+		}
+	}
+
+	// Initialize marshalers for the configured encodings
+	e.tracesEncoding = sdkEncoding(e.config.Traces.Encoding)
+	if e.tracesEncoding == otelnats.EncodingJSON {
+		e.tracesMarshaler = &ptrace.JSONMarshaler{}
+	} else {
+		e.tracesMarshaler = &ptrace.ProtoMarshaler{}
+	}
+	e.metricsEncoding = sdkEncoding(e.config.Metrics.Encoding)
+	if e.metricsEncoding == otelnats.EncodingJSON {
+		e.metricsMarshaler = &pmetric.JSONMarshaler{}
+	} else {
+		e.metricsMarshaler = &pmetric.ProtoMarshaler{}
+	}
+	e.logsEncoding = sdkEncoding(e.config.Logs.Encoding)
+	if e.logsEncoding == otelnats.EncodingJSON {
+		e.logsMarshaler = &plog.JSONMarshaler{}
+	} else {
+		e.logsMarshaler = &plog.ProtoMarshaler{}
+	}
+
+	// Initialize message ID generators for JetStream de-duplication
+	if js := e.config.Traces.JetStream; js != nil {
+		e.tracesMsgID = newMsgIDGenerator(js.MsgID)
+	}
+	if js := e.config.Metrics.JetStream; js != nil {
+		e.metricsMsgID = newMsgIDGenerator(js.MsgID)
+	}
+	if js := e.config.Logs.JetStream; js != nil {
+		e.logsMsgID = newMsgIDGenerator(js.MsgID)
+	}
+
+	conn, owned, err := internalnats.GetConnection(ctx, host, e.config.Connection, e.config.ClientConfig, e.logger)
 	if err != nil {
 		return err
 	}
 	e.conn = conn
+	e.ownsConn = owned
+
+	if err := internalnats.RegisterConnectionTelemetry(e.telemetry, conn); err != nil {
+		return err
+	}
 
-	// Initialize proto marshalers
-	e.tracesMarshaler = &ptrace.ProtoMarshaler{}
-	e.metricsMarshaler = &pmetric.ProtoMarshaler{}
-	e.logsMarshaler = &plog.ProtoMarshaler{}
+	// Set up publishers right away, or in the background once the connection
+	// is established if NATS is not reachable yet
+	e.stopDeferred, err = internalnats.StartWhenConnected(ctx, host, conn, e.logger, e.startConnected)
+	return err
+}
+
+// startConnected completes start once the connection is established.
+func (e *natsExporter) startConnected(ctx context.Context) error {
+	e.maxPayload = e.conn.MaxPayload()
+
+	// Provision streams before anything is published to them
+	if err := e.provisionStream(ctx, e.config.Traces, e.tracesSubject); err != nil {
+		return err
+	}
+	if err := e.provisionStream(ctx, e.config.Metrics, e.metricsSubject); err != nil {
+		return err
+	}
+	if err := e.provisionStream(ctx, e.config.Logs, e.logsSubject); err != nil {
+		return err
+	}
+
+	// Initialize per-signal publishers
+	var err error
+	if e.tracesPublisher, err = e.newPublisher(ctx, otelnats.SignalTraces, e.config.Traces); err != nil {
+		return err
+	}
+	if e.metricsPublisher, err = e.newPublisher(ctx, otelnats.SignalMetrics, e.config.Metrics); err != nil {
+		return err
+	}
+	if e.logsPublisher, err = e.newPublisher(ctx, otelnats.SignalLogs, e.config.Logs); err != nil {
+		return err
+	}
 
 	e.logger.Info("NATS exporter started",
-		zap.String("url", e.config.URL),
+		internalnats.ConnectionField(e.config.Connection, e.config.ClientConfig),
+		zap.Int64("max_payload", e.maxPayload),
 	)
+	e.started.Store(true)
 	return nil
 }
 
-func (e *natsExporter) shutdown(_ context.Context) error {
-	// Drain ensures all pending messages are sent before closing
-	if e.conn != nil {
-		return e.conn.Drain()
+// customHeaders returns the configured static headers and the configured
+// keys of the client metadata in ctx, to be added to each message.
+func (e *natsExporter) customHeaders(ctx context.Context) nats.Header {
+	headers := nats.Header{}
+	for name, value := range e.config.Headers {
+		headers.Set(name, value)
 	}
-	return nil
+	if len(e.config.FromClientMetadata) > 0 {
+		info := client.FromContext(ctx)
+		for _, key := range e.config.FromClientMetadata {
+			for _, value := range info.Metadata.Get(key) {
+				headers.Add(key, value)
+			}
+		}
+	}
+	return headers
+}
+
+// sdkEncoding maps a configured encoding onto the otelnats protocol encoding.
+// Anything other than otlp_json (including empty) is protobuf.
+func sdkEncoding(encoding string) otelnats.Encoding {
+	if encoding == encodingJSON {
+		return otelnats.EncodingJSON
+	}
+	return otelnats.EncodingProtobuf
+}
+
+// compress compresses data as configured and records the algorithm
+// in the Content-Encoding header.
+func compress(compression configcompression.Type, data []byte, headers nats.Header) ([]byte, error) {
+	if !compression.IsCompressed() {
+		return data, nil
+	}
+	compressed, err := internalnats.Compress(compression, data)
+	if err != nil {
+		return nil, err
+	}
+	headers.Set(internalnats.HeaderContentEncoding, string(compression))
+	return compressed, nil
+}
+
+// newPublisher returns a JetStream publisher if the signal has JetStream
+// configured, or a core NATS publisher otherwise. If claim-check is
+// configured, the publisher is wrapped to offload oversized payloads.
+func (e *natsExporter) newPublisher(ctx context.Context, signal string, cfg SignalConfig) (publisher, error) {
+	p, err := e.newBasePublisher(signal, cfg)
+	if err != nil || cfg.ClaimCheck == nil {
+		return p, err
+	}
+
+	js, err := e.jetStream()
+	if err != nil {
+		return nil, err
+	}
+	store, err := js.ObjectStore(ctx, cfg.ClaimCheck.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind object store %q: %w", cfg.ClaimCheck.Bucket, err)
+	}
+
+	// Anything larger than max_payload must be offloaded regardless of the threshold
+	threshold := int(e.maxPayload)
+	if cfg.ClaimCheck.Threshold > 0 && cfg.ClaimCheck.Threshold < threshold {
+		threshold = cfg.ClaimCheck.Threshold
+	}
+
+	return &claimCheckPublisher{
+		next:      p,
+		store:     store,
+		bucket:    cfg.ClaimCheck.Bucket,
+		threshold: threshold,
+		logger:    e.logger,
+	}, nil
+}
+
+// jetStream returns the shared JetStream context, creating it on first use.
+func (e *natsExporter) jetStream() (jetstream.JetStream, error) {
+	if e.js == nil {
+		js, err := jetstream.New(e.conn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+		}
+		e.js = js
+	}
+	return e.js, nil
+}
+
+// provisionStream creates or verifies the signal's stream if provisioning is
+// configured. Subjects default to the signal subject, all its partitions or
+// all its series subjects, which validation guarantees to be static in that case.
+func (e *natsExporter) provisionStream(ctx context.Context, cfg SignalConfig, subject *subjectTemplate) error {
+	if cfg.JetStream == nil || cfg.JetStream.Provision == nil {
+		return nil
+	}
+	p := cfg.JetStream.Provision
+	subjects := p.Subjects
+	if len(subjects) == 0 {
+		subjects = []string{subject.render(pcommon.NewMap())}
+		if cfg.Partitions > 0 {
+			subjects[0] += ".*"
+		} else if cfg.Snapshot {
+			subjects[0] += ".>"
+		}
+	}
+	maxMsgsPerSubject := p.MaxMsgsPerSubject
+	if maxMsgsPerSubject == 0 && cfg.Snapshot {
+		maxMsgsPerSubject = 1
+	}
+	// Values were checked by Config.Validate
+	retention, _ := internalnats.ParseRetention(p.Retention)
+	storage, _ := internalnats.ParseStorage(p.Storage)
+
+	js, err := e.jetStream()
+	if err != nil {
+		return err
+	}
+	return internalnats.ProvisionStream(ctx, js, p.Policy, jetstream.StreamConfig{
+		Name:              cfg.JetStream.Stream,
+		Subjects:          subjects,
+		Retention:         retention,
+		MaxAge:            p.MaxAge,
+		MaxBytes:          p.MaxBytes,
+		Replicas:          p.Replicas,
+		Storage:           storage,
+		MaxMsgsPerSubject: maxMsgsPerSubject,
+	}, e.logger)
+}
+
+// newBasePublisher returns the publisher for the signal's delivery mode.
+func (e *natsExporter) newBasePublisher(signal string, cfg SignalConfig) (publisher, error) {
+	if cfg.RequestReply != nil {
+		timeout := cfg.RequestReply.Timeout
+		if timeout == 0 {
+			timeout = defaultRequestTimeout
+		}
+		return &requestPublisher{conn: e.conn, timeout: timeout, logger: e.logger}, nil
+	}
+	if cfg.JetStream == nil {
+		return &corePublisher{conn: e.conn}, nil
+	}
+
+	js, err := e.jetStream()
+	if err != nil {
+		return nil, err
+	}
+
+	ackTimeout := cfg.JetStream.AckTimeout
+	if ackTimeout == 0 {
+		ackTimeout = defaultAckTimeout
+	}
+
+	if cfg.JetStream.MaxPending > 0 {
+		// Each async publisher gets its own JetStream context so that its
+		// pending count and flush on shutdown only cover its own messages.
+		js, err := jetstream.New(e.conn,
+			jetstream.WithPublishAsyncMaxPending(cfg.JetStream.MaxPending),
+			jetstream.WithPublishAsyncTimeout(ackTimeout),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+		}
+		return &jetStreamAsyncPublisher{
+			js:         js,
+			stream:     cfg.JetStream.Stream,
+			window:     make(chan struct{}, cfg.JetStream.MaxPending),
+			duplicates: e.newDuplicates(signal),
+		}, nil
+	}
+
+	return &jetStreamPublisher{
+		js:         js,
+		stream:     cfg.JetStream.Stream,
+		ackTimeout: ackTimeout,
+		duplicates: e.newDuplicates(signal),
+	}, nil
+}
+
+// newDuplicates returns the duplicates observer of a JetStream publisher.
+func (e *natsExporter) newDuplicates(signal string) duplicates {
+	return duplicates{
+		logger:  e.logger,
+		counter: e.telemetry.ExporterNatsDuplicateMessages,
+		attrs:   metric.WithAttributeSet(attribute.NewSet(attribute.String("signal", signal))),
+	}
+}
+
+func (e *natsExporter) shutdown(ctx context.Context) error {
+	if e.stopDeferred != nil {
+		e.stopDeferred()
+	}
+	if e.telemetry != nil {
+		e.telemetry.Shutdown()
+	}
+	if e.conn == nil {
+		return nil
+	}
+	if e.ownsConn {
+		defer e.conn.Close()
+	}
+
+	// Wait for in-flight async publishes within the shutdown context
+	var errs error
+	for _, p := range []publisher{e.tracesPublisher, e.metricsPublisher, e.logsPublisher} {
+		if f, ok := p.(flusher); ok {
+			errs = errors.Join(errs, f.flush(ctx))
+		}
+	}
+
+	// Flush buffered core NATS messages before closing
+	if !e.conn.IsConnected() {
+		return errs
+	}
+	if _, ok := ctx.Deadline(); ok {
+		errs = errors.Join(errs, e.conn.FlushWithContext(ctx))
+	} else {
+		errs = errors.Join(errs, e.conn.Flush())
+	}
+	return errs
 }
 
 func (e *natsExporter) publishTraces(ctx context.Context, td ptrace.Traces) error {
-	// Marshal pdata to OTLP protobuf bytes
+	if !e.started.Load() {
+		return internalnats.ErrNotConnected
+	}
+	var batches []subjectBatch[ptrace.Traces]
+	if e.tracesRouter != nil {
+		var err error
+		if batches, err = routeTraces(ctx, e.tracesRouter, td); err != nil {
+			return consumererror.NewPermanent(fmt.Errorf("failed to route traces: %w", err))
+		}
+	} else {
+		batches = tracesBySubject(td, e.tracesSubject)
+	}
+	if n := e.config.Traces.Partitions; n > 0 {
+		batches = partitionTraces(batches, n)
+	}
+	return sendBatches(batches, e.sendTracesFunc(ctx, false), tracesError)
+}
+
+func (e *natsExporter) sendTracesFunc(ctx context.Context, split bool) func(string, ptrace.Traces) error {
+	return func(subject string, td ptrace.Traces) error {
+		return e.sendTraces(ctx, subject, td, split)
+	}
+}
+
+// tracesError wraps err together with the traces that failed to publish.
+func tracesError(err error, failed []ptrace.Traces) error {
+	td := ptrace.NewTraces()
+	for _, f := range failed {
+		f.ResourceSpans().MoveAndAppendTo(td.ResourceSpans())
+	}
+	return consumererror.NewTraces(err, td)
+}
+
+func (e *natsExporter) publishMetrics(ctx context.Context, md pmetric.Metrics) error {
+	if !e.started.Load() {
+		return internalnats.ErrNotConnected
+	}
+	if e.config.Metrics.Snapshot {
+		prefix := e.metricsSubject.render(pcommon.NewMap())
+		return sendBatches(snapshotMetrics(md, prefix), e.sendMetricsFunc(ctx, false), metricsError)
+	}
+	var batches []subjectBatch[pmetric.Metrics]
+	if e.metricsRouter != nil {
+		var err error
+		if batches, err = routeMetrics(ctx, e.metricsRouter, md); err != nil {
+			return consumererror.NewPermanent(fmt.Errorf("failed to route metrics: %w", err))
+		}
+	} else {
+		batches = metricsBySubject(md, e.metricsSubject)
+	}
+	return sendBatches(batches, e.sendMetricsFunc(ctx, false), metricsError)
+}
+
+func (e *natsExporter) sendMetricsFunc(ctx context.Context, split bool) func(string, pmetric.Metrics) error {
+	return func(subject string, md pmetric.Metrics) error {
+		return e.sendMetrics(ctx, subject, md, split)
+	}
+}
+
+// metricsError wraps err together with the metrics that failed to publish.
+func metricsError(err error, failed []pmetric.Metrics) error {
+	md := pmetric.NewMetrics()
+	for _, f := range failed {
+		f.ResourceMetrics().MoveAndAppendTo(md.ResourceMetrics())
+	}
+	return consumererror.NewMetrics(err, md)
+}
+
+func (e *natsExporter) publishLogs(ctx context.Context, ld plog.Logs) error {
+	if !e.started.Load() {
+		return internalnats.ErrNotConnected
+	}
+	var batches []subjectBatch[plog.Logs]
+	if e.logsRouter != nil {
+		var err error
+		if batches, err = routeLogs(ctx, e.logsRouter, ld); err != nil {
+			return consumererror.NewPermanent(fmt.Errorf("failed to route logs: %w", err))
+		}
+	} else {
+		batches = logsBySubject(ld, e.logsSubject)
+	}
+	if n := e.config.Logs.Partitions; n > 0 {
+		batches = partitionLogs(batches, n, func() int {
+			return int(e.untracedLogs.Add(1) % uint64(n))
+		})
+	}
+	return sendBatches(batches, e.sendLogsFunc(ctx, false), logsError)
+}
+
+func (e *natsExporter) sendLogsFunc(ctx context.Context, split bool) func(string, plog.Logs) error {
+	return func(subject string, ld plog.Logs) error {
+		return e.sendLogs(ctx, subject, ld, split)
+	}
+}
+
+// logsError wraps err together with the logs that failed to publish.
+func logsError(err error, failed []plog.Logs) error {
+	ld := plog.NewLogs()
+	for _, f := range failed {
+		f.ResourceLogs().MoveAndAppendTo(ld.ResourceLogs())
+	}
+	return consumererror.NewLogs(err, ld)
+}
+
+// sendBatches publishes each batch with send.
+// A single batch is the caller's original data, so its error is returned as is.
+// When the input was split into several messages, retryable failures are
+// passed to wrap together with the data that failed, so that exporterhelper
+// only retries that data; permanent failures are dropped.
+func sendBatches[T any](
+	batches []subjectBatch[T],
+	send func(subject string, data T) error,
+	wrap func(err error, failed []T) error,
+) error {
+	if len(batches) == 1 {
+		return send(batches[0].subject, batches[0].data)
+	}
+
+	var retryErr, permErr error
+	var failed []T
+	for _, b := range batches {
+		err := send(b.subject, b.data)
+		switch {
+		case err == nil:
+		case consumererror.IsPermanent(err):
+			permErr = errors.Join(permErr, err)
+		default:
+			retryErr = errors.Join(retryErr, err)
+			failed = append(failed, failedData(err, b.data))
+		}
+	}
+
+	if retryErr != nil {
+		return wrap(retryErr, failed)
+	}
+	return permErr
+}
+
+// failedData returns the part of data that failed with err. If data was split
+// further and only some of it failed, that part is carried by a partial error.
+func failedData[T any](err error, data T) T {
+	var (
+		traces  consumererror.Traces
+		metrics consumererror.Metrics
+		logs    consumererror.Logs
+	)
+	var partial any
+	switch {
+	case errors.As(err, &traces):
+		partial = traces.Data()
+	case errors.As(err, &metrics):
+		partial = metrics.Data()
+	case errors.As(err, &logs):
+		partial = logs.Data()
+	}
+	if d, ok := partial.(T); ok {
+		return d
+	}
+	return data
+}
+
+// exceedsMaxPayload reports whether a message with the given data and headers
+// is larger than the server accepts.
+func (e *natsExporter) exceedsMaxPayload(data []byte, headers nats.Header) bool {
+	return e.maxPayload > 0 && int64(len(data)+headerSize(headers)) > e.maxPayload
+}
+
+// publish sends msg with p and records its latency and, once successful, its
+// size. Snapshot series subjects are recorded as their common prefix to keep
+// the subject attribute bounded.
+func (e *natsExporter) publish(ctx context.Context, p publisher, signal string, msg *nats.Msg) error {
+	start := time.Now()
+	err := p.publish(ctx, msg)
+	e.telemetry.ExporterNatsPublishDuration.Record(ctx, time.Since(start).Seconds(),
+		metric.WithAttributes(attribute.String("signal", signal)))
+	if err != nil {
+		return err
+	}
+
+	subject := msg.Subject
+	if signal == otelnats.SignalMetrics && e.config.Metrics.Snapshot {
+		subject = e.metricsSubject.render(pcommon.NewMap())
+	}
+	attrs := metric.WithAttributes(attribute.String("subject", subject))
+	e.telemetry.ExporterNatsPublishedMessages.Add(ctx, 1, attrs)
+	e.telemetry.ExporterNatsPublishedBytes.Add(ctx, int64(len(msg.Data)), attrs)
+	return nil
+}
+
+// sendTraces publishes td as a single message, splitting it if it exceeds
+// max_payload. split reports whether td is already part of a split batch.
+func (e *natsExporter) sendTraces(ctx context.Context, subject string, td ptrace.Traces, split bool) error {
+	// Marshal pdata to OTLP bytes
 	data, err := e.tracesMarshaler.MarshalTraces(td)
 	if err != nil {
 		return consumererror.NewPermanent(err)
 	}
 
-	// Use configured subject and SDK protocol headers
-	subject := e.config.Traces.Subject
-	headers := otelnats.BuildHeaders(ctx, otelnats.SignalTraces, otelnats.EncodingProtobuf, nil)
+	// Use SDK protocol headers
+	headers := otelnats.BuildHeaders(ctx, otelnats.SignalTraces, e.tracesEncoding, e.customHeaders)
+
+	data, err = compress(e.config.Traces.Compression, data, headers)
+	if err != nil {
+		return consumererror.NewPermanent(err)
+	}
+
+	if e.tracesMsgID != nil {
+		headers.Set(jetstream.MsgIDHeader, e.tracesMsgID.id(subject, data, tracesResources(td), split))
+	}
+
+	// Split oversized batches until every message fits, unless the
+	// claim-check publisher offloads them
+	if e.config.Traces.ClaimCheck == nil && e.exceedsMaxPayload(data, headers) {
+		left, right, ok := splitTraces(td)
+		if !ok {
+			e.telemetry.ExporterNatsDroppedOversizeMessages.Add(ctx, 1, metric.WithAttributes(attribute.String("signal", otelnats.SignalTraces)))
+			e.logger.Error("dropping traces exceeding max_payload",
+				zap.String("subject", subject),
+				zap.Int("spans", td.SpanCount()),
+				zap.Int("bytes", len(data)),
+				zap.Int64("max_payload", e.maxPayload),
+			)
+			return consumererror.NewPermanent(fmt.Errorf("dropped %d spans: %d bytes exceed max_payload of %d bytes", td.SpanCount(), len(data), e.maxPayload))
+		}
+		return sendBatches([]subjectBatch[ptrace.Traces]{{subject, left}, {subject, right}}, e.sendTracesFunc(ctx, true), tracesError)
+	}
+
+	if l := e.tracesLimiter; l != nil {
+		ok, rest, err := l.admit(ctx, len(data)+headerSize(headers), td)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// Shed: publish the priority records alone, if any
+			if rest.SpanCount() == 0 {
+				return nil
+			}
+			return e.sendTraces(ctx, subject, rest, split)
+		}
+	}
 
 	msg := &nats.Msg{
 		Subject: subject,
@@ -80,7 +705,7 @@ func (e *natsExporter) publishTraces(ctx context.Context, td ptrace.Traces) erro
 		Header:  headers,
 	}
 
-	if err := e.conn.PublishMsg(msg); err != nil {
+	if err := e.publish(ctx, e.tracesPublisher, otelnats.SignalTraces, msg); err != nil {
 		e.logger.Error("failed to publish traces",
 			zap.String("subject", subject),
 			zap.Error(err),
@@ -96,15 +721,56 @@ func (e *natsExporter) publishTraces(ctx context.Context, td ptrace.Traces) erro
 	return nil
 }
 
-func (e *natsExporter) publishMetrics(ctx context.Context, md pmetric.Metrics) error {
+// sendMetrics publishes md as a single message, splitting it if it exceeds
+// max_payload. split reports whether md is already part of a split batch.
+func (e *natsExporter) sendMetrics(ctx context.Context, subject string, md pmetric.Metrics, split bool) error {
 	data, err := e.metricsMarshaler.MarshalMetrics(md)
 	if err != nil {
 		return consumererror.NewPermanent(err)
 	}
 
-	// Use configured subject and SDK protocol headers
-	subject := e.config.Metrics.Subject
-	headers := otelnats.BuildHeaders(ctx, otelnats.SignalMetrics, otelnats.EncodingProtobuf, nil)
+	// Use SDK protocol headers
+	headers := otelnats.BuildHeaders(ctx, otelnats.SignalMetrics, e.metricsEncoding, e.customHeaders)
+
+	data, err = compress(e.config.Metrics.Compression, data, headers)
+	if err != nil {
+		return consumererror.NewPermanent(err)
+	}
+
+	if e.metricsMsgID != nil {
+		headers.Set(jetstream.MsgIDHeader, e.metricsMsgID.id(subject, data, metricsResources(md), split))
+	}
+
+	// Split oversized batches until every message fits, unless the
+	// claim-check publisher offloads them
+	if e.config.Metrics.ClaimCheck == nil && e.exceedsMaxPayload(data, headers) {
+		left, right, ok := splitMetrics(md)
+		if !ok {
+			e.telemetry.ExporterNatsDroppedOversizeMessages.Add(ctx, 1, metric.WithAttributes(attribute.String("signal", otelnats.SignalMetrics)))
+			e.logger.Error("dropping metrics exceeding max_payload",
+				zap.String("subject", subject),
+				zap.Int("data_points", md.DataPointCount()),
+				zap.Int("bytes", len(data)),
+				zap.Int64("max_payload", e.maxPayload),
+			)
+			return consumererror.NewPermanent(fmt.Errorf("dropped %d data points: %d bytes exceed max_payload of %d bytes", md.DataPointCount(), len(data), e.maxPayload))
+		}
+		return sendBatches([]subjectBatch[pmetric.Metrics]{{subject, left}, {subject, right}}, e.sendMetricsFunc(ctx, true), metricsError)
+	}
+
+	if l := e.metricsLimiter; l != nil {
+		ok, rest, err := l.admit(ctx, len(data)+headerSize(headers), md)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// Shed: publish the priority records alone, if any
+			if rest.DataPointCount() == 0 {
+				return nil
+			}
+			return e.sendMetrics(ctx, subject, rest, split)
+		}
+	}
 
 	msg := &nats.Msg{
 		Subject: subject,
@@ -112,7 +778,7 @@ func (e *natsExporter) publishMetrics(ctx context.Context, md pmetric.Metrics) e
 		Header:  headers,
 	}
 
-	if err := e.conn.PublishMsg(msg); err != nil {
+	if err := e.publish(ctx, e.metricsPublisher, otelnats.SignalMetrics, msg); err != nil {
 		e.logger.Error("failed to publish metrics",
 			zap.String("subject", subject),
 			zap.Error(err),
@@ -128,15 +794,56 @@ func (e *natsExporter) publishMetrics(ctx context.Context, md pmetric.Metrics) e
 	return nil
 }
 
-func (e *natsExporter) publishLogs(ctx context.Context, ld plog.Logs) error {
+// sendLogs publishes ld as a single message, splitting it if it exceeds
+// max_payload. split reports whether ld is already part of a split batch.
+func (e *natsExporter) sendLogs(ctx context.Context, subject string, ld plog.Logs, split bool) error {
 	data, err := e.logsMarshaler.MarshalLogs(ld)
 	if err != nil {
 		return consumererror.NewPermanent(err)
 	}
 
-	// Use configured subject and SDK protocol headers
-	subject := e.config.Logs.Subject
-	headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+	// Use SDK protocol headers
+	headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, e.logsEncoding, e.customHeaders)
+
+	data, err = compress(e.config.Logs.Compression, data, headers)
+	if err != nil {
+		return consumererror.NewPermanent(err)
+	}
+
+	if e.logsMsgID != nil {
+		headers.Set(jetstream.MsgIDHeader, e.logsMsgID.id(subject, data, logsResources(ld), split))
+	}
+
+	// Split oversized batches until every message fits, unless the
+	// claim-check publisher offloads them
+	if e.config.Logs.ClaimCheck == nil && e.exceedsMaxPayload(data, headers) {
+		left, right, ok := splitLogs(ld)
+		if !ok {
+			e.telemetry.ExporterNatsDroppedOversizeMessages.Add(ctx, 1, metric.WithAttributes(attribute.String("signal", otelnats.SignalLogs)))
+			e.logger.Error("dropping logs exceeding max_payload",
+				zap.String("subject", subject),
+				zap.Int("log_records", ld.LogRecordCount()),
+				zap.Int("bytes", len(data)),
+				zap.Int64("max_payload", e.maxPayload),
+			)
+			return consumererror.NewPermanent(fmt.Errorf("dropped %d log records: %d bytes exceed max_payload of %d bytes", ld.LogRecordCount(), len(data), e.maxPayload))
+		}
+		return sendBatches([]subjectBatch[plog.Logs]{{subject, left}, {subject, right}}, e.sendLogsFunc(ctx, true), logsError)
+	}
+
+	if l := e.logsLimiter; l != nil {
+		ok, rest, err := l.admit(ctx, len(data)+headerSize(headers), ld)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// Shed: publish the priority records alone, if any
+			if rest.LogRecordCount() == 0 {
+				return nil
+			}
+			return e.sendLogs(ctx, subject, rest, split)
+		}
+	}
 
 	msg := &nats.Msg{
 		Subject: subject,
@@ -144,7 +851,7 @@ func (e *natsExporter) publishLogs(ctx context.Context, ld plog.Logs) error {
 		Header:  headers,
 	}
 
-	if err := e.conn.PublishMsg(msg); err != nil {
+	if err := e.publish(ctx, e.logsPublisher, otelnats.SignalLogs, msg); err != nil {
 		e.logger.Error("failed to publish logs",
 			zap.String("subject", subject),
 			zap.Error(err),
@@ -2,19 +2,37 @@ package natsexporter
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/mikluko/otelnats"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
 	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configcompression"
+	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/exporter/exportertest"
+	"go.opentelemetry.io/collector/extension/extensiontest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 
 	"github.com/mikluko/otelnats-collector/internal/metadata"
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+	"github.com/mikluko/otelnats-collector/internal/natsextension"
 	"github.com/mikluko/otelnats-collector/internal/testutil"
 )
 
@@ -176,3 +194,1164 @@ func TestE2E_Logs(t *testing.T) {
 		t.Fatal("timeout waiting for logs")
 	}
 }
+
+func TestE2E_TracesJetStream(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "OTEL",
+		Subjects: []string{"test.traces"},
+	})
+	require.NoError(t, err)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Traces.Subject = "test.traces"
+	cfg.Traces.JetStream = &JetStreamConfig{Stream: "OTEL"}
+
+	set := exportertest.NewNopSettings(metadata.Type)
+	exp, err := factory.CreateTraces(ctx, set, cfg)
+	require.NoError(t, err)
+
+	err = exp.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer exp.Shutdown(ctx)
+
+	traces := ptrace.NewTraces()
+	span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetName("test-span")
+
+	err = exp.ConsumeTraces(ctx, traces)
+	require.NoError(t, err)
+
+	// The PubAck has been received, so the message must already be stored
+	info, err := stream.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), info.State.Msgs)
+
+	raw, err := stream.GetMsg(ctx, 1)
+	require.NoError(t, err)
+	got, err := (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(raw.Data)
+	require.NoError(t, err)
+	assert.Equal(t, "test-span", got.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name())
+}
+
+func TestE2E_LogsJetStreamAsync(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "OTEL",
+		Subjects: []string{"test.logs"},
+	})
+	require.NoError(t, err)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.JetStream = &JetStreamConfig{Stream: "OTEL", MaxPending: 2}
+
+	exp := newNatsExporter(cfg, exportertest.NewNopSettings(metadata.Type))
+	require.NoError(t, exp.start(ctx, componenttest.NewNopHost()))
+
+	// Publish more batches concurrently than the window allows
+	const batches = 10
+	errs := make(chan error, batches)
+	for i := 0; i < batches; i++ {
+		go func() {
+			logs := plog.NewLogs()
+			logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+			errs <- exp.publishLogs(ctx, logs)
+		}()
+	}
+	for i := 0; i < batches; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	require.NoError(t, exp.shutdown(shutdownCtx))
+
+	info, err := stream.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(batches), info.State.Msgs)
+}
+
+func TestJetStreamPublish_Errors(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "OTEL",
+		Subjects: []string{"test.logs"},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		subject       string
+		stream        string
+		maxPending    int
+		wantPermanent bool
+	}{
+		{
+			name:          "stream mismatch is permanent",
+			subject:       "test.logs",
+			stream:        "OTHER",
+			wantPermanent: true,
+		},
+		{
+			name:          "no stream bound to subject is retryable",
+			subject:       "test.unbound",
+			wantPermanent: false,
+		},
+		{
+			name:          "async stream mismatch is permanent",
+			subject:       "test.logs",
+			stream:        "OTHER",
+			maxPending:    4,
+			wantPermanent: true,
+		},
+		{
+			name:          "async no stream bound to subject is retryable",
+			subject:       "test.unbound",
+			maxPending:    4,
+			wantPermanent: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factory := NewFactory()
+			cfg := factory.CreateDefaultConfig().(*Config)
+			cfg.ClientConfig.URL = ns.ClientURL()
+			cfg.Logs.Subject = tt.subject
+			cfg.Logs.JetStream = &JetStreamConfig{
+				Stream:     tt.stream,
+				AckTimeout: time.Second,
+				MaxPending: tt.maxPending,
+			}
+
+			exp := newNatsExporter(cfg, exportertest.NewNopSettings(metadata.Type))
+			require.NoError(t, exp.start(ctx, componenttest.NewNopHost()))
+			defer exp.shutdown(ctx)
+
+			logs := plog.NewLogs()
+			logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+
+			err := exp.publishLogs(ctx, logs)
+			require.Error(t, err)
+			assert.Equal(t, tt.wantPermanent, consumererror.IsPermanent(err))
+		})
+	}
+}
+
+func TestE2E_LogsSubjectTemplate(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	received := make(chan *nats.Msg, 10)
+	sub, err := nc.ChanSubscribe("otel.logs.>", received)
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "otel.${signal}.${attr:k8s.cluster.name}.${attr:k8s.namespace.name}"
+
+	set := exportertest.NewNopSettings(metadata.Type)
+	exp, err := factory.CreateLogs(ctx, set, cfg)
+	require.NoError(t, err)
+
+	err = exp.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer exp.Shutdown(ctx)
+
+	logs := plog.NewLogs()
+	for _, r := range []struct{ cluster, namespace string }{
+		{"prod", "payments"},
+		{"prod", "checkout"},
+		{"prod", "payments"},
+	} {
+		rl := logs.ResourceLogs().AppendEmpty()
+		rl.Resource().Attributes().PutStr("k8s.cluster.name", r.cluster)
+		rl.Resource().Attributes().PutStr("k8s.namespace.name", r.namespace)
+		rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(r.namespace)
+	}
+
+	err = exp.ConsumeLogs(ctx, logs)
+	require.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-received:
+			got, err := (&plog.ProtoUnmarshaler{}).UnmarshalLogs(msg.Data)
+			require.NoError(t, err)
+			counts[msg.Subject] = got.LogRecordCount()
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for logs")
+		}
+	}
+	assert.Equal(t, map[string]int{
+		"otel.logs.prod.payments": 2,
+		"otel.logs.prod.checkout": 1,
+	}, counts)
+}
+
+func TestSendBatches_PartialFailure(t *testing.T) {
+	batches := []subjectBatch[string]{
+		{subject: "ok", data: "a"},
+		{subject: "retryable", data: "b"},
+		{subject: "permanent", data: "c"},
+	}
+	send := func(subject string, _ string) error {
+		switch subject {
+		case "retryable":
+			return errors.New("timeout")
+		case "permanent":
+			return consumererror.NewPermanent(errors.New("rejected"))
+		}
+		return nil
+	}
+
+	var gotFailed []string
+	err := sendBatches(batches, send, func(err error, failed []string) error {
+		gotFailed = failed
+		return err
+	})
+	require.Error(t, err)
+	assert.False(t, consumererror.IsPermanent(err))
+	assert.Equal(t, []string{"b"}, gotFailed)
+}
+
+func TestE2E_MetricsJSONEncoding(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	received := make(chan *nats.Msg, 1)
+	sub, err := nc.ChanSubscribe("test.metrics", received)
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Metrics.Subject = "test.metrics"
+	cfg.Metrics.Encoding = "otlp_json"
+
+	set := exportertest.NewNopSettings(metadata.Type)
+	exp, err := factory.CreateMetrics(ctx, set, cfg)
+	require.NoError(t, err)
+
+	err = exp.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer exp.Shutdown(ctx)
+
+	metrics := pmetric.NewMetrics()
+	m := metrics.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("test.counter")
+	m.SetEmptyGauge().DataPoints().AppendEmpty().SetIntValue(42)
+
+	err = exp.ConsumeMetrics(ctx, metrics)
+	require.NoError(t, err)
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, otelnats.ContentTypeJSON, msg.Header.Get(otelnats.HeaderContentType))
+		assert.Equal(t, otelnats.SignalMetrics, msg.Header.Get(otelnats.HeaderOtelSignal))
+		assert.True(t, json.Valid(msg.Data))
+
+		got, err := (&pmetric.JSONUnmarshaler{}).UnmarshalMetrics(msg.Data)
+		require.NoError(t, err)
+		assert.Equal(t, "test.counter", got.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Name())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for metrics")
+	}
+}
+
+func TestE2E_TracesCompression(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	received := make(chan *nats.Msg, 1)
+	sub, err := nc.ChanSubscribe("test.traces", received)
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Traces.Subject = "test.traces"
+	cfg.Traces.Compression = configcompression.TypeZstd
+
+	set := exportertest.NewNopSettings(metadata.Type)
+	exp, err := factory.CreateTraces(ctx, set, cfg)
+	require.NoError(t, err)
+
+	err = exp.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer exp.Shutdown(ctx)
+
+	traces := ptrace.NewTraces()
+	ss := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty()
+	for i := 0; i < 100; i++ {
+		ss.Spans().AppendEmpty().SetName("test-span")
+	}
+
+	err = exp.ConsumeTraces(ctx, traces)
+	require.NoError(t, err)
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "zstd", msg.Header.Get(internalnats.HeaderContentEncoding))
+
+		dec, err := internalnats.NewDecompressor(1 << 20)
+		require.NoError(t, err)
+		defer dec.Close()
+
+		data, err := dec.Decompress(msg.Header.Get(internalnats.HeaderContentEncoding), msg.Data)
+		require.NoError(t, err)
+		assert.Less(t, len(msg.Data), len(data))
+
+		got, err := (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(data)
+		require.NoError(t, err)
+		assert.Equal(t, 100, got.SpanCount())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for traces")
+	}
+}
+
+func TestE2E_LogsSplitByMaxPayload(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithMaxPayload(t, 2048)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	received := make(chan *nats.Msg, 100)
+	sub, err := nc.ChanSubscribe("test.logs", received)
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+
+	set := exportertest.NewNopSettings(metadata.Type)
+	exp, err := factory.CreateLogs(ctx, set, cfg)
+	require.NoError(t, err)
+
+	err = exp.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer exp.Shutdown(ctx)
+
+	// 50 records of ~200 bytes need several messages
+	logs := plog.NewLogs()
+	lrs := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	for i := 0; i < 50; i++ {
+		lrs.AppendEmpty().Body().SetStr(strings.Repeat("x", 200))
+	}
+	err = exp.ConsumeLogs(ctx, logs)
+	require.NoError(t, err)
+
+	total := 0
+	for total < 50 {
+		select {
+		case msg := <-received:
+			assert.LessOrEqual(t, len(msg.Data)+headerSize(msg.Header), 2048)
+			got, err := (&plog.ProtoUnmarshaler{}).UnmarshalLogs(msg.Data)
+			require.NoError(t, err)
+			total += got.LogRecordCount()
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for logs, received %d records", total)
+		}
+	}
+	assert.Equal(t, 50, total)
+
+	// A single record larger than max_payload is dropped as permanent
+	logs = plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(strings.Repeat("x", 4096))
+	err = exp.ConsumeLogs(ctx, logs)
+	require.Error(t, err)
+	assert.True(t, consumererror.IsPermanent(err))
+	assert.Contains(t, err.Error(), "exceed max_payload")
+}
+
+func TestSendBatches_NestedPartialFailure(t *testing.T) {
+	failedHalf := plog.NewLogs()
+	failedHalf.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("failed")
+
+	batches := []subjectBatch[plog.Logs]{
+		{subject: "split", data: plog.NewLogs()},
+		{subject: "ok", data: plog.NewLogs()},
+	}
+	send := func(subject string, _ plog.Logs) error {
+		if subject == "split" {
+			// Only part of a nested split failed
+			return consumererror.NewLogs(errors.New("timeout"), failedHalf)
+		}
+		return nil
+	}
+
+	err := sendBatches(batches, send, logsError)
+	var partial consumererror.Logs
+	require.ErrorAs(t, err, &partial)
+	assert.Equal(t, 1, partial.Data().LogRecordCount())
+}
+
+func TestE2E_LogsClaimCheck(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	store, err := js.CreateObjectStore(ctx, jetstream.ObjectStoreConfig{Bucket: "otel-payloads"})
+	require.NoError(t, err)
+
+	received := make(chan *nats.Msg, 2)
+	sub, err := nc.ChanSubscribe("test.logs", received)
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.ClaimCheck = &ClaimCheckConfig{Bucket: "otel-payloads", Threshold: 1024}
+
+	set := exportertest.NewNopSettings(metadata.Type)
+	exp, err := factory.CreateLogs(ctx, set, cfg)
+	require.NoError(t, err)
+
+	err = exp.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer exp.Shutdown(ctx)
+
+	// Small payloads are published inline
+	small := plog.NewLogs()
+	small.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("small")
+	require.NoError(t, exp.ConsumeLogs(ctx, small))
+
+	// Payloads above the threshold are uploaded and referenced
+	large := plog.NewLogs()
+	large.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(strings.Repeat("x", 4096))
+	require.NoError(t, exp.ConsumeLogs(ctx, large))
+
+	for _, want := range []string{"small", strings.Repeat("x", 4096)} {
+		select {
+		case msg := <-received:
+			data := msg.Data
+			if object := msg.Header.Get(internalnats.HeaderClaimCheckObject); object != "" {
+				assert.Empty(t, msg.Data)
+				assert.Equal(t, "otel-payloads", msg.Header.Get(internalnats.HeaderClaimCheckBucket))
+				assert.Equal(t, "application/x-protobuf", msg.Header.Get(otelnats.HeaderContentType))
+				data, err = store.GetBytes(ctx, object)
+				require.NoError(t, err)
+			}
+			got, err := (&plog.ProtoUnmarshaler{}).UnmarshalLogs(data)
+			require.NoError(t, err)
+			assert.Equal(t, want, got.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for logs")
+		}
+	}
+}
+
+func TestE2E_TracesSharedConnection(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	received := make(chan *nats.Msg, 1)
+	sub, err := nc.ChanSubscribe("test.traces", received)
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	// Start the connection extension
+	extID := component.MustNewIDWithName("nats", "main")
+	extFactory := natsextension.NewFactory()
+	extCfg := extFactory.CreateDefaultConfig().(*natsextension.Config)
+	extCfg.URL = ns.ClientURL()
+	ext, err := extFactory.Create(ctx, extensiontest.NewNopSettings(metadata.Type), extCfg)
+	require.NoError(t, err)
+	host := testutil.NewHost(map[component.ID]component.Component{extID: ext})
+	require.NoError(t, ext.Start(ctx, host))
+	defer ext.Shutdown(ctx)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = "nats://unreachable:4222"
+	cfg.Connection = &extID
+	cfg.Traces.Subject = "test.traces"
+
+	set := exportertest.NewNopSettings(metadata.Type)
+	exp, err := factory.CreateTraces(ctx, set, cfg)
+	require.NoError(t, err)
+	require.NoError(t, exp.Start(ctx, host))
+
+	traces := ptrace.NewTraces()
+	traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("test-span")
+	require.NoError(t, exp.ConsumeTraces(ctx, traces))
+
+	select {
+	case msg := <-received:
+		got, err := (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(msg.Data)
+		require.NoError(t, err)
+		assert.Equal(t, 1, got.SpanCount())
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for traces")
+	}
+
+	// Shutting down the exporter leaves the shared connection open
+	require.NoError(t, exp.Shutdown(ctx))
+	assert.True(t, ext.(internalnats.ConnectionExtension).Conn().IsConnected())
+}
+
+func TestStart_MissingConnectionExtension(t *testing.T) {
+	ctx := context.Background()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	extID := component.MustNewIDWithName("nats", "missing")
+	cfg.Connection = &extID
+
+	exp, err := factory.CreateTraces(ctx, exportertest.NewNopSettings(metadata.Type), cfg)
+	require.NoError(t, err)
+
+	err = exp.Start(ctx, componenttest.NewNopHost())
+	assert.EqualError(t, err, `connection extension "nats/missing" not found`)
+}
+
+func TestE2E_LogsJetStreamMsgID(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:       "OTEL",
+		Subjects:   []string{"test.logs"},
+		Duplicates: time.Minute,
+	})
+	require.NoError(t, err)
+
+	for i, maxPending := range []int{0, 4} {
+		tel := componenttest.NewTelemetry()
+
+		factory := NewFactory()
+		cfg := factory.CreateDefaultConfig().(*Config)
+		cfg.ClientConfig.URL = ns.ClientURL()
+		cfg.Logs.Subject = "test.logs"
+		cfg.Logs.JetStream = &JetStreamConfig{MaxPending: maxPending, MsgID: "content_hash"}
+
+		set := exportertest.NewNopSettings(metadata.Type)
+		set.TelemetrySettings = tel.NewTelemetrySettings()
+		exp := newNatsExporter(cfg, set)
+		require.NoError(t, exp.start(ctx, componenttest.NewNopHost()))
+
+		// Publishing the same batch twice, as a retry after a lost PubAck would,
+		// stores it once
+		logs := plog.NewLogs()
+		logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(fmt.Sprintf("retried %d", i))
+		require.NoError(t, exp.publishLogs(ctx, logs))
+		require.NoError(t, exp.publishLogs(ctx, logs))
+		require.NoError(t, exp.shutdown(ctx))
+
+		info, err := stream.Info(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(i+1), info.State.Msgs)
+
+		m, err := tel.GetMetric("otelcol_exporter_nats_duplicate_messages")
+		require.NoError(t, err)
+		sum := m.Data.(metricdata.Sum[int64])
+		require.Len(t, sum.DataPoints, 1)
+		assert.Equal(t, int64(1), sum.DataPoints[0].Value)
+		assert.Equal(t, attribute.NewSet(attribute.String("signal", "logs")), sum.DataPoints[0].Attributes)
+		require.NoError(t, tel.Shutdown(ctx))
+	}
+}
+
+func TestE2E_LogsClaimCheckDuplicate(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:       "OTEL",
+		Subjects:   []string{"test.logs"},
+		Duplicates: time.Minute,
+	})
+	require.NoError(t, err)
+	store, err := js.CreateObjectStore(ctx, jetstream.ObjectStoreConfig{Bucket: "otel-payloads"})
+	require.NoError(t, err)
+
+	for i, maxPending := range []int{0, 4} {
+		factory := NewFactory()
+		cfg := factory.CreateDefaultConfig().(*Config)
+		cfg.ClientConfig.URL = ns.ClientURL()
+		cfg.Logs.Subject = "test.logs"
+		cfg.Logs.JetStream = &JetStreamConfig{MaxPending: maxPending, MsgID: "content_hash"}
+		cfg.Logs.ClaimCheck = &ClaimCheckConfig{Bucket: "otel-payloads", Threshold: 1024}
+
+		exp := newNatsExporter(cfg, exportertest.NewNopSettings(metadata.Type))
+		require.NoError(t, exp.start(ctx, componenttest.NewNopHost()))
+
+		// A retry uploads the payload again, but its reference is a duplicate,
+		// so its object is deleted rather than left unreferenced
+		logs := plog.NewLogs()
+		logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(fmt.Sprintf("%d%s", i, strings.Repeat("x", 4096)))
+		require.NoError(t, exp.publishLogs(ctx, logs))
+		require.NoError(t, exp.publishLogs(ctx, logs))
+		require.NoError(t, exp.shutdown(ctx))
+
+		msg, err := stream.GetMsg(ctx, uint64(i+1))
+		require.NoError(t, err)
+		objects, err := store.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, objects, i+1)
+		_, err = store.GetInfo(ctx, msg.Header.Get(internalnats.HeaderClaimCheckObject))
+		assert.NoError(t, err, "the stored reference must resolve")
+	}
+}
+
+func TestE2E_LogsJetStreamProvision(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.JetStream = &JetStreamConfig{
+		Stream: "OTEL",
+		Provision: &StreamProvisionConfig{
+			Policy:  internalnats.ProvisionReconcile,
+			MaxAge:  time.Hour,
+			Storage: "memory",
+		},
+	}
+
+	exp := newNatsExporter(cfg, exportertest.NewNopSettings(metadata.Type))
+	require.NoError(t, exp.start(ctx, componenttest.NewNopHost()))
+	defer exp.shutdown(ctx)
+
+	// The stream is created with the signal subject
+	stream, err := js.Stream(ctx, "OTEL")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test.logs"}, stream.CachedInfo().Config.Subjects)
+	assert.Equal(t, time.Hour, stream.CachedInfo().Config.MaxAge)
+	assert.Equal(t, jetstream.MemoryStorage, stream.CachedInfo().Config.Storage)
+
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	require.NoError(t, exp.publishLogs(ctx, logs))
+
+	info, err := stream.Info(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), info.State.Msgs)
+
+	// verify_only rejects drift instead of fixing it
+	cfg.Logs.JetStream.Provision.Policy = internalnats.ProvisionVerifyOnly
+	cfg.Logs.JetStream.Provision.MaxAge = 2 * time.Hour
+	exp = newNatsExporter(cfg, exportertest.NewNopSettings(metadata.Type))
+	err = exp.start(ctx, componenttest.NewNopHost())
+	require.ErrorContains(t, err, `stream "OTEL" does not match configuration: max_age 1h0m0s != 2h0m0s`)
+	require.NoError(t, exp.shutdown(ctx))
+}
+
+func TestE2E_LogsRequestReply(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.RequestReply = &RequestReplyConfig{Timeout: time.Second}
+
+	exp := newNatsExporter(cfg, exportertest.NewNopSettings(metadata.Type))
+	require.NoError(t, exp.start(ctx, componenttest.NewNopHost()))
+	defer exp.shutdown(ctx)
+
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+
+	// Nobody is listening yet
+	err = exp.publishLogs(ctx, logs)
+	require.ErrorIs(t, err, nats.ErrNoResponders)
+	assert.False(t, consumererror.IsPermanent(err))
+
+	// The responder answers according to the request body
+	var reply func(msg *nats.Msg) *nats.Msg
+	sub, err := nc.Subscribe("test.logs", func(msg *nats.Msg) {
+		require.NoError(t, msg.RespondMsg(reply(msg)))
+	})
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	tests := []struct {
+		name      string
+		reply     func(msg *nats.Msg) *nats.Msg
+		wantErr   string
+		permanent bool
+	}{
+		{
+			name: "success",
+			reply: func(msg *nats.Msg) *nats.Msg {
+				data, err := plogotlp.NewExportResponse().MarshalProto()
+				require.NoError(t, err)
+				return &nats.Msg{Subject: msg.Reply, Data: data}
+			},
+		},
+		{
+			name: "partial success",
+			reply: func(msg *nats.Msg) *nats.Msg {
+				resp := plogotlp.NewExportResponse()
+				resp.PartialSuccess().SetRejectedLogRecords(1)
+				resp.PartialSuccess().SetErrorMessage("invalid record")
+				data, err := resp.MarshalProto()
+				require.NoError(t, err)
+				return &nats.Msg{Subject: msg.Reply, Data: data}
+			},
+			wantErr:   "receiver rejected 1 log records: invalid record",
+			permanent: true,
+		},
+		{
+			name: "retryable error",
+			reply: func(msg *nats.Msg) *nats.Msg {
+				reply := nats.NewMsg(msg.Reply)
+				reply.Header.Set(internalnats.HeaderExportError, "queue is full")
+				return reply
+			},
+			wantErr: "receiver failed to export logs: queue is full",
+		},
+		{
+			name: "permanent error",
+			reply: func(msg *nats.Msg) *nats.Msg {
+				reply := nats.NewMsg(msg.Reply)
+				reply.Header.Set(internalnats.HeaderExportError, "cannot decode")
+				reply.Header.Set(internalnats.HeaderExportErrorPermanent, "true")
+				return reply
+			},
+			wantErr:   "receiver failed to export logs: cannot decode",
+			permanent: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply = tt.reply
+			err := exp.publishLogs(ctx, logs)
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.wantErr)
+			assert.Equal(t, tt.permanent, consumererror.IsPermanent(err))
+		})
+	}
+}
+
+func TestE2E_LogsSendingQueue(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.JetStream = &JetStreamConfig{Stream: "OTEL", AckTimeout: 100 * time.Millisecond}
+	cfg.BackOffConfig.InitialInterval = 10 * time.Millisecond
+	cfg.BackOffConfig.MaxInterval = 50 * time.Millisecond
+	cfg.QueueConfig.GetOrInsertDefault()
+
+	exp, err := factory.CreateLogs(ctx, exportertest.NewNopSettings(metadata.Type), cfg)
+	require.NoError(t, err)
+	require.NoError(t, exp.Start(ctx, componenttest.NewNopHost()))
+
+	// The stream does not exist yet, so the batch is queued instead of failing
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	require.NoError(t, exp.ConsumeLogs(ctx, logs))
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "OTEL",
+		Subjects: []string{"test.logs"},
+	})
+	require.NoError(t, err)
+
+	// The queued batch is delivered once the stream appears
+	require.Eventually(t, func() bool {
+		info, err := stream.Info(ctx)
+		return err == nil && info.State.Msgs == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, exp.Shutdown(ctx))
+}
+
+func TestE2E_LogsHeaders(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	sub, err := nc.SubscribeSync("test.logs")
+	require.NoError(t, err)
+	require.NoError(t, nc.Flush())
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Headers = map[string]string{"X-Cluster": "edge-1"}
+	cfg.FromClientMetadata = []string{"X-Tenant-Id", "X-Missing"}
+
+	exp := newNatsExporter(cfg, exportertest.NewNopSettings(metadata.Type))
+	require.NoError(t, exp.start(ctx, componenttest.NewNopHost()))
+	defer exp.shutdown(ctx)
+
+	ctx = client.NewContext(ctx, client.Info{
+		Metadata: client.NewMetadata(map[string][]string{"x-tenant-id": {"acme"}}),
+	})
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	require.NoError(t, exp.publishLogs(ctx, logs))
+
+	msg, err := sub.NextMsg(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "edge-1", msg.Header.Get("X-Cluster"))
+	assert.Equal(t, "acme", msg.Header.Get("X-Tenant-Id"))
+	assert.NotContains(t, msg.Header, "X-Missing")
+	assert.Equal(t, otelnats.SignalLogs, msg.Header.Get(otelnats.HeaderOtelSignal))
+}
+
+func TestE2E_LogsRoutes(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	all, err := nc.SubscribeSync("test.logs")
+	require.NoError(t, err)
+	errs, err := nc.SubscribeSync("test.logs.errors")
+	require.NoError(t, err)
+	require.NoError(t, nc.Flush())
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.Routes = []RouteConfig{
+		{Context: "log", Condition: "severity_number >= SEVERITY_NUMBER_ERROR", Subject: "test.logs.errors"},
+	}
+	require.NoError(t, cfg.Validate())
+
+	exp := newNatsExporter(cfg, exportertest.NewNopSettings(metadata.Type))
+	require.NoError(t, exp.start(ctx, componenttest.NewNopHost()))
+	defer exp.shutdown(ctx)
+
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	for _, severity := range []plog.SeverityNumber{plog.SeverityNumberInfo, plog.SeverityNumberError, plog.SeverityNumberWarn} {
+		records.AppendEmpty().SetSeverityNumber(severity)
+	}
+	require.NoError(t, exp.publishLogs(ctx, logs))
+
+	msg, err := errs.NextMsg(time.Second)
+	require.NoError(t, err)
+	got, err := (&plog.ProtoUnmarshaler{}).UnmarshalLogs(msg.Data)
+	require.NoError(t, err)
+	require.Equal(t, 1, got.LogRecordCount())
+	assert.Equal(t, plog.SeverityNumberError, got.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).SeverityNumber())
+
+	msg, err = all.NextMsg(time.Second)
+	require.NoError(t, err)
+	got, err = (&plog.ProtoUnmarshaler{}).UnmarshalLogs(msg.Data)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.LogRecordCount())
+}
+
+func TestE2E_TracesPartitions(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	sub, err := nc.SubscribeSync("test.traces.*")
+	require.NoError(t, err)
+	require.NoError(t, nc.Flush())
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Traces.Subject = "test.traces"
+	cfg.Traces.Partitions = 4
+
+	exp := newNatsExporter(cfg, exportertest.NewNopSettings(metadata.Type))
+	require.NoError(t, exp.start(ctx, componenttest.NewNopHost()))
+	defer exp.shutdown(ctx)
+
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	for i := byte(0); i < 16; i++ {
+		spans.AppendEmpty().SetTraceID(pcommon.TraceID{i % 8})
+	}
+	require.NoError(t, exp.publishTraces(ctx, td))
+
+	total := 0
+	for total < 16 {
+		msg, err := sub.NextMsg(time.Second)
+		require.NoError(t, err)
+		got, err := (&ptrace.ProtoUnmarshaler{}).UnmarshalTraces(msg.Data)
+		require.NoError(t, err)
+		for _, span := range got.ResourceSpans().At(0).ScopeSpans().At(0).Spans().All() {
+			assert.Equal(t, partitionSubject("test.traces", tracePartition(span.TraceID(), 4)), msg.Subject)
+			total++
+		}
+	}
+}
+
+func TestE2E_MetricsSnapshot(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Metrics.Subject = "test.snapshot"
+	cfg.Metrics.Snapshot = true
+	cfg.Metrics.JetStream = &JetStreamConfig{
+		Stream:    "OTEL_SNAPSHOT",
+		Provision: &StreamProvisionConfig{Storage: "memory"},
+	}
+	require.NoError(t, cfg.Validate())
+
+	exp := newNatsExporter(cfg, exportertest.NewNopSettings(metadata.Type))
+	require.NoError(t, exp.start(ctx, componenttest.NewNopHost()))
+	defer exp.shutdown(ctx)
+
+	// The stream covers all series subjects and keeps one message each
+	stream, err := js.Stream(ctx, "OTEL_SNAPSHOT")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test.snapshot.>"}, stream.CachedInfo().Config.Subjects)
+	assert.Equal(t, int64(1), stream.CachedInfo().Config.MaxMsgsPerSubject)
+
+	gauge := func(value float64) pmetric.Metrics {
+		md := pmetric.NewMetrics()
+		m := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+		m.SetName("queue.depth")
+		dps := m.SetEmptyGauge().DataPoints()
+		for _, queue := range []string{"a", "b"} {
+			dp := dps.AppendEmpty()
+			dp.Attributes().PutStr("queue", queue)
+			dp.SetDoubleValue(value)
+		}
+		return md
+	}
+	require.NoError(t, exp.publishMetrics(ctx, gauge(1)))
+	require.NoError(t, exp.publishMetrics(ctx, gauge(2)))
+
+	info, err := stream.Info(ctx, jetstream.WithSubjectFilter("test.snapshot.>"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), info.State.Msgs)
+	require.Len(t, info.State.Subjects, 2)
+	for subject := range info.State.Subjects {
+		msg, err := stream.GetLastMsgForSubject(ctx, subject)
+		require.NoError(t, err)
+		md, err := (&pmetric.ProtoUnmarshaler{}).UnmarshalMetrics(msg.Data)
+		require.NoError(t, err)
+		assert.Equal(t, 2.0, md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).DoubleValue())
+	}
+}
+
+func TestE2E_LogsTelemetry(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	tel := componenttest.NewTelemetry()
+	defer tel.Shutdown(ctx)
+
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+
+	set := exportertest.NewNopSettings(metadata.Type)
+	set.TelemetrySettings = tel.NewTelemetrySettings()
+	exp := newNatsExporter(cfg, set)
+	require.NoError(t, exp.start(ctx, componenttest.NewNopHost()))
+	defer exp.shutdown(ctx)
+
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("metered")
+	require.NoError(t, exp.publishLogs(ctx, logs))
+	require.NoError(t, exp.publishLogs(ctx, logs))
+
+	subject := attribute.NewSet(attribute.String("subject", "test.logs"))
+	m, err := tel.GetMetric("otelcol_exporter_nats_published_messages")
+	require.NoError(t, err)
+	sum := m.Data.(metricdata.Sum[int64])
+	require.Len(t, sum.DataPoints, 1)
+	assert.Equal(t, int64(2), sum.DataPoints[0].Value)
+	assert.Equal(t, subject, sum.DataPoints[0].Attributes)
+
+	m, err = tel.GetMetric("otelcol_exporter_nats_published_bytes")
+	require.NoError(t, err)
+	assert.Positive(t, m.Data.(metricdata.Sum[int64]).DataPoints[0].Value)
+
+	m, err = tel.GetMetric("otelcol_exporter_nats_publish_duration")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), m.Data.(metricdata.Histogram[float64]).DataPoints[0].Count)
+
+	m, err = tel.GetMetric("otelcol_nats_reconnects")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), m.Data.(metricdata.Sum[int64]).DataPoints[0].Value)
+}
+
+func TestE2E_LogsDeferredConnect(t *testing.T) {
+	port := testutil.FreePort(t)
+	ctx := context.Background()
+
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = fmt.Sprintf("nats://127.0.0.1:%d", port)
+	cfg.ReconnectWait = 10 * time.Millisecond
+	cfg.RetryOnFailedInitialConnect = true
+	cfg.Logs.Subject = "test.logs"
+
+	// Start succeeds without a server and reports the missing connection
+	host := testutil.NewHost(nil)
+	exp := newNatsExporter(cfg, exportertest.NewNopSettings(metadata.Type))
+	require.NoError(t, exp.start(ctx, host))
+	defer exp.shutdown(ctx)
+	assert.Equal(t, []componentstatus.Status{componentstatus.StatusRecoverableError}, host.Statuses())
+
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("deferred")
+	err := exp.publishLogs(ctx, logs)
+	require.ErrorIs(t, err, internalnats.ErrNotConnected)
+	assert.False(t, consumererror.IsPermanent(err))
+
+	ns := testutil.StartEmbeddedNATSOnPort(t, port)
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+	sub, err := nc.SubscribeSync("test.logs")
+	require.NoError(t, err)
+	require.NoError(t, nc.Flush())
+
+	require.Eventually(t, func() bool {
+		return exp.publishLogs(ctx, logs) == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = sub.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+
+	statuses := host.Statuses()
+	assert.Equal(t, componentstatus.StatusOK, statuses[len(statuses)-1])
+}
+
+func TestE2E_LogsDeferredConnectGivesUp(t *testing.T) {
+	port := testutil.FreePort(t)
+	ctx := context.Background()
+
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = fmt.Sprintf("nats://127.0.0.1:%d", port)
+	cfg.ReconnectWait = 10 * time.Millisecond
+	cfg.MaxReconnects = 1
+	cfg.RetryOnFailedInitialConnect = true
+	cfg.Logs.Subject = "test.logs"
+
+	// With no server, the connection closes once its reconnects run out
+	host := testutil.NewHost(nil)
+	exp := newNatsExporter(cfg, exportertest.NewNopSettings(metadata.Type))
+	require.NoError(t, exp.start(ctx, host))
+	defer exp.shutdown(ctx)
+
+	require.Eventually(t, func() bool {
+		statuses := host.Statuses()
+		return statuses[len(statuses)-1] == componentstatus.StatusPermanentError
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []componentstatus.Status{
+		componentstatus.StatusRecoverableError,
+		componentstatus.StatusPermanentError,
+	}, host.Statuses())
+}
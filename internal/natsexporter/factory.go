@@ -2,8 +2,10 @@ package natsexporter
 
 import (
 	"context"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configoptional"
 	"go.opentelemetry.io/collector/config/configretry"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/exporter"
@@ -17,7 +19,15 @@ const (
 	defaultTracesSubject  = "otel.traces"
 	defaultMetricsSubject = "otel.metrics"
 	defaultLogsSubject    = "otel.logs"
-	defaultEncoding       = "otlp_proto"
+	defaultEncoding       = encodingProto
+	defaultAckTimeout     = 5 * time.Second
+	defaultRequestTimeout = 5 * time.Second
+
+	encodingProto = "otlp_proto"
+	encodingJSON  = "otlp_json"
+
+	// defaultSubjectFallback replaces ${attr:key} when the attribute is missing.
+	defaultSubjectFallback = "unknown"
 )
 
 // NewFactory creates a factory for the NATS exporter.
@@ -35,6 +45,7 @@ func createDefaultConfig() component.Config {
 	return &Config{
 		TimeoutConfig: exporterhelper.NewDefaultTimeoutConfig(),
 		BackOffConfig: configretry.NewDefaultBackOffConfig(),
+		QueueConfig:   configoptional.Default(exporterhelper.NewDefaultQueueConfig()),
 		ClientConfig:  internalnats.NewDefaultClientConfig(),
 		Traces: SignalConfig{
 			Subject:  defaultTracesSubject,
@@ -69,6 +80,7 @@ func createTracesExporter(
 		exporterhelper.WithShutdown(exp.shutdown),
 		exporterhelper.WithTimeout(config.TimeoutConfig),
 		exporterhelper.WithRetry(config.BackOffConfig),
+		exporterhelper.WithQueue(config.QueueConfig),
 	)
 }
 
@@ -90,6 +102,7 @@ func createMetricsExporter(
 		exporterhelper.WithShutdown(exp.shutdown),
 		exporterhelper.WithTimeout(config.TimeoutConfig),
 		exporterhelper.WithRetry(config.BackOffConfig),
+		exporterhelper.WithQueue(config.QueueConfig),
 	)
 }
 
@@ -111,5 +124,6 @@ func createLogsExporter(
 		exporterhelper.WithShutdown(exp.shutdown),
 		exporterhelper.WithTimeout(config.TimeoutConfig),
 		exporterhelper.WithRetry(config.BackOffConfig),
+		exporterhelper.WithQueue(config.QueueConfig),
 	)
 }
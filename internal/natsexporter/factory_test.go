@@ -7,10 +7,12 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/component/componenttest"
 	"go.opentelemetry.io/collector/exporter/exportertest"
 
 	"github.com/mikluko/otelnats-collector/internal/metadata"
+	"github.com/mikluko/otelnats-collector/internal/testutil"
 )
 
 func TestNewFactory(t *testing.T) {
@@ -75,3 +77,32 @@ func TestCreateLogsExporter(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, exp)
 }
+
+func TestCreateDefaultConfig_SendingQueue(t *testing.T) {
+	cfg := NewFactory().CreateDefaultConfig().(*Config)
+
+	// The queue is disabled unless configured, but carries the defaults
+	assert.False(t, cfg.QueueConfig.HasValue())
+	cfg.QueueConfig.GetOrInsertDefault()
+	assert.Positive(t, cfg.QueueConfig.Get().NumConsumers)
+	assert.Positive(t, cfg.QueueConfig.Get().QueueSize)
+}
+
+func TestCreateLogsExporter_MissingStorage(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	queue := cfg.QueueConfig.GetOrInsertDefault()
+	storageID := component.MustNewID("file_storage")
+	queue.StorageID = &storageID
+
+	ctx := context.Background()
+	exp, err := factory.CreateLogs(ctx, exportertest.NewNopSettings(metadata.Type), cfg)
+	require.NoError(t, err)
+
+	err = exp.Start(ctx, componenttest.NewNopHost())
+	assert.ErrorContains(t, err, "no storage client extension found")
+	require.NoError(t, exp.Shutdown(ctx))
+}
@@ -0,0 +1,103 @@
+package natsexporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+const (
+	msgIDContentHash = "content_hash"
+	msgIDAttrPrefix  = "attr:"
+)
+
+// msgIDGenerator derives Nats-Msg-Id values so that the stream's duplicate
+// window drops batches published again by retries.
+type msgIDGenerator struct {
+	// attr is the resource attribute identifying a batch;
+	// if empty, IDs are content hashes
+	attr string
+}
+
+// newMsgIDGenerator returns a generator for the configured msg_id mode,
+// or nil if message IDs are disabled.
+func newMsgIDGenerator(mode string) *msgIDGenerator {
+	switch mode {
+	case "":
+		return nil
+	case msgIDContentHash:
+		return &msgIDGenerator{}
+	}
+	return &msgIDGenerator{attr: strings.TrimPrefix(mode, msgIDAttrPrefix)}
+}
+
+// id returns the message ID for a payload published to subject.
+//
+// In attribute mode the ID is the subject and the attribute values of the
+// resources in the message. Messages split to fit max_payload share those
+// values and resources lacking the attribute cannot be identified, so both
+// fall back to a hash of the subject and payload.
+func (g *msgIDGenerator) id(subject string, payload []byte, resources []pcommon.Resource, split bool) string {
+	if g.attr != "" && !split {
+		if v, ok := resourceAttrValues(resources, g.attr); ok {
+			return subject + ":" + v
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(subject))
+	h.Write([]byte{0})
+	h.Write(payload)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// resourceAttrValues returns the distinct values of attribute key across
+// resources, joined in order of first appearance. It reports false if any
+// resource lacks the attribute.
+func resourceAttrValues(resources []pcommon.Resource, key string) (string, bool) {
+	if len(resources) == 0 {
+		return "", false
+	}
+	var values []string
+	seen := map[string]bool{}
+	for _, res := range resources {
+		v, ok := res.Attributes().Get(key)
+		if !ok || v.AsString() == "" {
+			return "", false
+		}
+		if s := v.AsString(); !seen[s] {
+			seen[s] = true
+			values = append(values, s)
+		}
+	}
+	return strings.Join(values, ","), true
+}
+
+func tracesResources(td ptrace.Traces) []pcommon.Resource {
+	resources := make([]pcommon.Resource, td.ResourceSpans().Len())
+	for i := range resources {
+		resources[i] = td.ResourceSpans().At(i).Resource()
+	}
+	return resources
+}
+
+func metricsResources(md pmetric.Metrics) []pcommon.Resource {
+	resources := make([]pcommon.Resource, md.ResourceMetrics().Len())
+	for i := range resources {
+		resources[i] = md.ResourceMetrics().At(i).Resource()
+	}
+	return resources
+}
+
+func logsResources(ld plog.Logs) []pcommon.Resource {
+	resources := make([]pcommon.Resource, ld.ResourceLogs().Len())
+	for i := range resources {
+		resources[i] = ld.ResourceLogs().At(i).Resource()
+	}
+	return resources
+}
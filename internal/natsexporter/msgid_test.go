@@ -0,0 +1,39 @@
+package natsexporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestMsgIDGenerator(t *testing.T) {
+	assert.Nil(t, newMsgIDGenerator(""))
+
+	payload := []byte("payload")
+	resource := func(attrs map[string]any) pcommon.Resource {
+		res := pcommon.NewResource()
+		_ = res.Attributes().FromRaw(attrs)
+		return res
+	}
+	batch := []pcommon.Resource{
+		resource(map[string]any{"batch.id": "b-1"}),
+		resource(map[string]any{"batch.id": "b-2"}),
+		resource(map[string]any{"batch.id": "b-1"}),
+	}
+
+	hash := newMsgIDGenerator("content_hash")
+	id := hash.id("otel.logs", payload, batch, false)
+	assert.Len(t, id, 64)
+	assert.Equal(t, id, hash.id("otel.logs", payload, nil, false), "content hash ignores resources")
+	assert.NotEqual(t, id, hash.id("otel.traces", payload, nil, false), "subject is part of the hash")
+	assert.NotEqual(t, id, hash.id("otel.logs", []byte("other"), nil, false))
+
+	attr := newMsgIDGenerator("attr:batch.id")
+	assert.Equal(t, "otel.logs:b-1,b-2", attr.id("otel.logs", payload, batch, false))
+
+	// Split messages and resources without the attribute use the content hash
+	assert.Equal(t, id, attr.id("otel.logs", payload, batch, true))
+	missing := append(batch, resource(nil))
+	assert.Equal(t, id, attr.id("otel.logs", payload, missing, false))
+}
@@ -0,0 +1,88 @@
+package natsexporter
+
+import (
+	"hash/fnv"
+	"strconv"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// tracePartition returns the partition of a trace ID. It matches the
+// partition function of NATS subject mapping applied to a subject token
+// holding the hex-encoded trace ID: FNV-1a (32 bit) modulo n.
+func tracePartition(id pcommon.TraceID, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id.String()))
+	return int(h.Sum32() % uint32(n))
+}
+
+// partitionSubject appends the partition token to subject.
+func partitionSubject(subject string, p int) string {
+	return subject + "." + strconv.Itoa(p)
+}
+
+// partitionTraces splits each batch by the trace ID of its spans into
+// n partitions, published to <subject>.<partition>. A batch whose spans all
+// fall into one partition is not copied.
+func partitionTraces(batches []subjectBatch[ptrace.Traces], n int) []subjectBatch[ptrace.Traces] {
+	var partitioned []subjectBatch[ptrace.Traces]
+	for _, b := range batches {
+		var parts []int
+		for _, rs := range b.data.ResourceSpans().All() {
+			for _, ss := range rs.ScopeSpans().All() {
+				for _, span := range ss.Spans().All() {
+					parts = append(parts, tracePartition(span.TraceID(), n))
+				}
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		distinct, single := destinations(parts)
+		for _, p := range distinct {
+			data := b.data
+			if !single {
+				data = selectTraces(b.data, parts, p)
+			}
+			partitioned = append(partitioned, subjectBatch[ptrace.Traces]{subject: partitionSubject(b.subject, p), data: data})
+		}
+	}
+	return partitioned
+}
+
+// partitionLogs is partitionTraces for log records. Records without a trace ID
+// go to the partition returned by untraced, called once per record, which
+// callers rotate to spread them evenly.
+func partitionLogs(batches []subjectBatch[plog.Logs], n int, untraced func() int) []subjectBatch[plog.Logs] {
+	var partitioned []subjectBatch[plog.Logs]
+	for _, b := range batches {
+		var parts []int
+		for _, rl := range b.data.ResourceLogs().All() {
+			for _, sl := range rl.ScopeLogs().All() {
+				for _, lr := range sl.LogRecords().All() {
+					var p int
+					if lr.TraceID().IsEmpty() {
+						p = untraced()
+					} else {
+						p = tracePartition(lr.TraceID(), n)
+					}
+					parts = append(parts, p)
+				}
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		distinct, single := destinations(parts)
+		for _, p := range distinct {
+			data := b.data
+			if !single {
+				data = selectLogs(b.data, parts, p)
+			}
+			partitioned = append(partitioned, subjectBatch[plog.Logs]{subject: partitionSubject(b.subject, p), data: data})
+		}
+	}
+	return partitioned
+}
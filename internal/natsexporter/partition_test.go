@@ -0,0 +1,83 @@
+package natsexporter
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestTracePartition_MatchesSubjectMapping(t *testing.T) {
+	transform, err := server.NewSubjectTransform("otel.traces.*", "otel.traces.{{partition(8,1)}}")
+	require.NoError(t, err)
+
+	for i := byte(0); i < 32; i++ {
+		id := pcommon.TraceID{i, 0xab, 0xcd, 15: i * 7}
+		want, err := transform.Match("otel.traces." + id.String())
+		require.NoError(t, err)
+		assert.Equal(t, want, partitionSubject("otel.traces", tracePartition(id, 8)))
+	}
+}
+
+func TestPartitionTraces(t *testing.T) {
+	ids := []pcommon.TraceID{{1}, {2}, {3}, {4}}
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "api")
+	spans := rs.ScopeSpans().AppendEmpty().Spans()
+	for _, id := range append(ids, ids...) {
+		spans.AppendEmpty().SetTraceID(id)
+	}
+
+	batches := partitionTraces([]subjectBatch[ptrace.Traces]{{subject: "otel.traces", data: td}}, 2)
+	total := 0
+	for _, b := range batches {
+		rs := b.data.ResourceSpans().At(0)
+		v, _ := rs.Resource().Attributes().Get("service.name")
+		assert.Equal(t, "api", v.Str())
+		for _, span := range rs.ScopeSpans().At(0).Spans().All() {
+			assert.Equal(t, partitionSubject("otel.traces", tracePartition(span.TraceID(), 2)), b.subject)
+			total++
+		}
+	}
+	assert.Equal(t, 8, total)
+
+	// A batch of a single trace is not copied
+	single := ptrace.NewTraces()
+	single.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetTraceID(ids[0])
+	batches = partitionTraces([]subjectBatch[ptrace.Traces]{{subject: "otel.traces", data: single}}, 4)
+	require.Len(t, batches, 1)
+	assert.Equal(t, single, batches[0].data)
+}
+
+func TestPartitionLogs_Untraced(t *testing.T) {
+	id := pcommon.TraceID{1}
+	ld := plog.NewLogs()
+	records := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().SetTraceID(id)
+	for range 4 {
+		records.AppendEmpty()
+	}
+
+	// Untraced records rotate over all partitions, one record at a time
+	next := 0
+	untraced := func() int {
+		next++
+		return next % 4
+	}
+	batches := partitionLogs([]subjectBatch[plog.Logs]{{subject: "otel.logs", data: ld}}, 4, untraced)
+	counts := map[string]int{}
+	for _, b := range batches {
+		counts[b.subject] = b.data.LogRecordCount()
+	}
+	want := map[string]int{}
+	for p := range 4 {
+		want[partitionSubject("otel.logs", p)] = 1
+	}
+	want[partitionSubject("otel.logs", tracePartition(id, 4))]++
+	assert.Equal(t, want, counts)
+}
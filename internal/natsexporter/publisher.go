@@ -0,0 +1,181 @@
+package natsexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// publisher sends a single message to NATS.
+// Returned errors are classified: permanent errors are wrapped with
+// consumererror.NewPermanent, everything else is retryable.
+type publisher interface {
+	publish(ctx context.Context, msg *nats.Msg) error
+}
+
+// flusher is implemented by publishers that may still have messages
+// in flight when the exporter shuts down.
+type flusher interface {
+	flush(ctx context.Context) error
+}
+
+// ackPublisher is implemented by publishers that wait for the stream's
+// PubAck, for callers that need to know whether the message was stored.
+type ackPublisher interface {
+	publishAck(ctx context.Context, msg *nats.Msg) (*jetstream.PubAck, error)
+}
+
+// corePublisher publishes with core NATS (at-most-once delivery).
+type corePublisher struct {
+	conn *nats.Conn
+}
+
+func (p *corePublisher) publish(_ context.Context, msg *nats.Msg) error {
+	err := p.conn.PublishMsg(msg)
+	if errors.Is(err, nats.ErrMaxPayload) || errors.Is(err, nats.ErrBadSubject) {
+		return consumererror.NewPermanent(err)
+	}
+	return err
+}
+
+// duplicates counts messages a stream acknowledged as duplicates of a message
+// stored earlier with the same Nats-Msg-Id, typically a retry after a lost PubAck.
+// The PubAck is the only place a duplicate is visible: the stream drops it, so
+// receivers never see it.
+type duplicates struct {
+	logger  *zap.Logger
+	counter metric.Int64Counter
+	attrs   metric.MeasurementOption
+}
+
+func (d *duplicates) observe(ctx context.Context, msg *nats.Msg, ack *jetstream.PubAck) {
+	if ack == nil || !ack.Duplicate {
+		return
+	}
+	d.counter.Add(ctx, 1, d.attrs)
+	d.logger.Debug("stream rejected duplicate message",
+		zap.String("subject", msg.Subject),
+		zap.String("stream", ack.Stream),
+		zap.String("msg_id", msg.Header.Get(jetstream.MsgIDHeader)),
+	)
+}
+
+// jetStreamPublisher publishes through JetStream and waits for the PubAck,
+// so a batch is only successful once a stream has stored it.
+type jetStreamPublisher struct {
+	js         jetstream.JetStream
+	stream     string
+	ackTimeout time.Duration
+	duplicates duplicates
+}
+
+func (p *jetStreamPublisher) publish(ctx context.Context, msg *nats.Msg) error {
+	_, err := p.publishAck(ctx, msg)
+	return err
+}
+
+func (p *jetStreamPublisher) publishAck(ctx context.Context, msg *nats.Msg) (*jetstream.PubAck, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.ackTimeout)
+	defer cancel()
+
+	var opts []jetstream.PublishOpt
+	if p.stream != "" {
+		opts = append(opts, jetstream.WithExpectStream(p.stream))
+	}
+
+	ack, err := p.js.PublishMsg(ctx, msg, opts...)
+	if err != nil {
+		return nil, classifyJetStreamError(err)
+	}
+	p.duplicates.observe(ctx, msg, ack)
+	return ack, nil
+}
+
+// jetStreamAsyncPublisher publishes through JetStream without blocking the
+// connection on each PubAck. Up to cap(window) publishes are in flight at once;
+// each publish call still waits for its own PubAck before returning.
+type jetStreamAsyncPublisher struct {
+	js         jetstream.JetStream
+	stream     string
+	window     chan struct{}
+	duplicates duplicates
+}
+
+func (p *jetStreamAsyncPublisher) publish(ctx context.Context, msg *nats.Msg) error {
+	_, err := p.publishAck(ctx, msg)
+	return err
+}
+
+func (p *jetStreamAsyncPublisher) publishAck(ctx context.Context, msg *nats.Msg) (*jetstream.PubAck, error) {
+	// Acquire a slot in the in-flight window
+	select {
+	case p.window <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	var opts []jetstream.PublishOpt
+	if p.stream != "" {
+		opts = append(opts, jetstream.WithExpectStream(p.stream))
+	}
+
+	future, err := p.js.PublishMsgAsync(msg, opts...)
+	if err != nil {
+		<-p.window
+		return nil, classifyJetStreamError(err)
+	}
+
+	select {
+	case ack := <-future.Ok():
+		<-p.window
+		p.duplicates.observe(ctx, msg, ack)
+		return ack, nil
+	case err := <-future.Err():
+		<-p.window
+		return nil, classifyJetStreamError(err)
+	case <-ctx.Done():
+		// The message is still in flight; keep its slot until the future
+		// resolves. The JetStream ack timeout guarantees that it will.
+		go func() {
+			select {
+			case <-future.Ok():
+			case <-future.Err():
+			}
+			<-p.window
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+// flush waits until all in-flight publishes are acknowledged or ctx expires.
+func (p *jetStreamAsyncPublisher) flush(ctx context.Context) error {
+	select {
+	case <-p.js.PublishAsyncComplete():
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%d JetStream publishes still pending: %w", p.js.PublishAsyncPending(), ctx.Err())
+	}
+}
+
+// classifyJetStreamError maps a JetStream publish error onto the collector's
+// retry semantics. Timeouts, missing responders and server-side unavailability
+// are retryable; requests the server rejected as invalid (wrong stream, message
+// too large, bad subject) will never succeed and are marked permanent.
+func classifyJetStreamError(err error) error {
+	var apiErr *jetstream.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusBadRequest {
+		return consumererror.NewPermanent(err)
+	}
+	if errors.Is(err, nats.ErrMaxPayload) || errors.Is(err, nats.ErrBadSubject) {
+		return consumererror.NewPermanent(err)
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package natsexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+func TestClassifyJetStreamError(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantPermanent bool
+	}{
+		{
+			name:          "stream mismatch",
+			err:           fmt.Errorf("nats: %w", &jetstream.APIError{Code: 400, ErrorCode: 10060, Description: "expected stream does not match"}),
+			wantPermanent: true,
+		},
+		{
+			name:          "max payload",
+			err:           nats.ErrMaxPayload,
+			wantPermanent: true,
+		},
+		{
+			name:          "storage unavailable",
+			err:           fmt.Errorf("nats: %w", &jetstream.APIError{Code: 503, Description: "jetstream not enabled"}),
+			wantPermanent: false,
+		},
+		{
+			name:          "no stream response",
+			err:           jetstream.ErrNoStreamResponse,
+			wantPermanent: false,
+		},
+		{
+			name:          "async ack timeout",
+			err:           jetstream.ErrAsyncPublishTimeout,
+			wantPermanent: false,
+		},
+		{
+			name:          "ack timeout",
+			err:           context.DeadlineExceeded,
+			wantPermanent: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyJetStreamError(tt.err)
+			assert.True(t, errors.Is(got, tt.err))
+			assert.Equal(t, tt.wantPermanent, consumererror.IsPermanent(got))
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package natsexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottldatapoint"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottllog"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlresource"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlspan"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/ottlfuncs"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/time/rate"
+
+	"github.com/mikluko/otelnats-collector/internal/metadata"
+)
+
+// Overflow policies for messages exceeding the rate limit.
+const (
+	overflowBlock  = "block"
+	overflowReject = "reject"
+	overflowShed   = "shed"
+)
+
+// errRateLimited is returned under the reject policy. It is retryable, so
+// exporterhelper retries the data with backoff or keeps it queued.
+var errRateLimited = errors.New("publish rate limit exceeded")
+
+// rateLimiter limits the messages and payload bytes per second published
+// for a signal carrying data of type T.
+type rateLimiter[T any] struct {
+	// messages and bytes are nil if the respective limit is disabled
+	messages *rate.Limiter
+	bytes    *rate.Limiter
+	overflow string
+
+	// count returns the number of records in data
+	count func(T) int
+
+	// prioritize returns a copy of data holding only its priority records
+	prioritize func(context.Context, T) (T, error)
+
+	telemetry *metadata.TelemetryBuilder
+	attrs     metric.MeasurementOption
+}
+
+func newRateLimiter[T any](
+	cfg *RateLimitConfig,
+	signal string,
+	count func(T) int,
+	prioritize func(context.Context, T) (T, error),
+	telemetry *metadata.TelemetryBuilder,
+) *rateLimiter[T] {
+	l := &rateLimiter[T]{
+		overflow:   cfg.Overflow,
+		count:      count,
+		prioritize: prioritize,
+		telemetry:  telemetry,
+		attrs:      metric.WithAttributeSet(attribute.NewSet(attribute.String("signal", signal))),
+	}
+	if l.overflow == "" {
+		l.overflow = overflowBlock
+	}
+	if cfg.MessagesPerSecond > 0 {
+		l.messages = rate.NewLimiter(rate.Limit(cfg.MessagesPerSecond), max(1, int(math.Ceil(cfg.MessagesPerSecond))))
+	}
+	if cfg.BytesPerSecond > 0 {
+		l.bytes = rate.NewLimiter(rate.Limit(cfg.BytesPerSecond), cfg.BytesPerSecond)
+	}
+	return l
+}
+
+// admit applies the limits to a message of size bytes carrying data and
+// returns whether it may be published. A message that is shed instead is
+// not published: rest holds its priority records, possibly none, which the
+// caller publishes in its place.
+func (l *rateLimiter[T]) admit(ctx context.Context, size int, data T) (ok bool, rest T, err error) {
+	delay, cancel := l.reserve(size)
+	if delay == 0 {
+		return true, data, nil
+	}
+
+	switch l.overflow {
+	case overflowReject:
+		cancel()
+		return false, data, errRateLimited
+	case overflowShed:
+		rest, err := l.prioritize(ctx, data)
+		if err != nil {
+			cancel()
+			return false, data, consumererror.NewPermanent(fmt.Errorf("failed to evaluate priority: %w", err))
+		}
+		if dropped := l.count(data) - l.count(rest); dropped > 0 {
+			cancel()
+			l.telemetry.ExporterNatsShedRecords.Add(ctx, int64(dropped), l.attrs)
+			return false, rest, nil
+		}
+		// Only priority records are left, they wait like under the block policy
+	}
+
+	l.telemetry.ExporterNatsRateLimitWait.Record(ctx, delay.Seconds(), l.attrs)
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true, data, nil
+	case <-ctx.Done():
+		cancel()
+		return false, data, ctx.Err()
+	}
+}
+
+// reserve takes the tokens of a message of size bytes. It returns the delay
+// until they are available and a function returning them to the buckets.
+func (l *rateLimiter[T]) reserve(size int) (time.Duration, func()) {
+	now := time.Now()
+	var reservations []*rate.Reservation
+	if l.messages != nil {
+		reservations = append(reservations, l.messages.ReserveN(now, 1))
+	}
+	if l.bytes != nil {
+		// A message larger than the bucket takes all of it
+		reservations = append(reservations, l.bytes.ReserveN(now, min(size, l.bytes.Burst())))
+	}
+
+	var delay time.Duration
+	for _, r := range reservations {
+		delay = max(delay, r.DelayFrom(now))
+	}
+	return delay, func() {
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+	}
+}
+
+// newTracesRateLimiter returns the rate limiter of the traces signal.
+func newTracesRateLimiter(cfg *RateLimitConfig, set component.TelemetrySettings, telemetry *metadata.TelemetryBuilder) (*rateLimiter[ptrace.Traces], error) {
+	prioritize := func(context.Context, ptrace.Traces) (ptrace.Traces, error) {
+		return ptrace.NewTraces(), nil
+	}
+	if cfg.Priority != nil {
+		r, err := newTracesPriority(cfg.Priority, set)
+		if err != nil {
+			return nil, err
+		}
+		prioritize = func(ctx context.Context, td ptrace.Traces) (ptrace.Traces, error) {
+			dests, err := traceDestinations(ctx, r, td)
+			if err != nil {
+				return ptrace.Traces{}, err
+			}
+			return selectTraces(td, dests, 0), nil
+		}
+	}
+	return newRateLimiter(cfg, "traces", ptrace.Traces.SpanCount, prioritize, telemetry), nil
+}
+
+// newMetricsRateLimiter returns the rate limiter of the metrics signal.
+func newMetricsRateLimiter(cfg *RateLimitConfig, set component.TelemetrySettings, telemetry *metadata.TelemetryBuilder) (*rateLimiter[pmetric.Metrics], error) {
+	prioritize := func(context.Context, pmetric.Metrics) (pmetric.Metrics, error) {
+		return pmetric.NewMetrics(), nil
+	}
+	if cfg.Priority != nil {
+		r, err := newMetricsPriority(cfg.Priority, set)
+		if err != nil {
+			return nil, err
+		}
+		prioritize = func(ctx context.Context, md pmetric.Metrics) (pmetric.Metrics, error) {
+			dests, err := metricDestinations(ctx, r, md)
+			if err != nil {
+				return pmetric.Metrics{}, err
+			}
+			return selectMetrics(md, dests, 0), nil
+		}
+	}
+	return newRateLimiter(cfg, "metrics", pmetric.Metrics.DataPointCount, prioritize, telemetry), nil
+}
+
+// newLogsRateLimiter returns the rate limiter of the logs signal.
+func newLogsRateLimiter(cfg *RateLimitConfig, set component.TelemetrySettings, telemetry *metadata.TelemetryBuilder) (*rateLimiter[plog.Logs], error) {
+	prioritize := func(context.Context, plog.Logs) (plog.Logs, error) {
+		return plog.NewLogs(), nil
+	}
+	if cfg.Priority != nil {
+		r, err := newLogsPriority(cfg.Priority, set)
+		if err != nil {
+			return nil, err
+		}
+		prioritize = func(ctx context.Context, ld plog.Logs) (plog.Logs, error) {
+			dests, err := logDestinations(ctx, r, ld)
+			if err != nil {
+				return plog.Logs{}, err
+			}
+			return selectLogs(ld, dests, 0), nil
+		}
+	}
+	return newRateLimiter(cfg, "logs", plog.Logs.LogRecordCount, prioritize, telemetry), nil
+}
+
+// newPriorityRouter parses a priority condition into a router with a single
+// route, so that priority records are those with destination 0.
+func newPriorityRouter[I interface{ Close() }](
+	cfg *PriorityConfig,
+	itemContext string,
+	parseItem func(string) (*ottl.Condition[I], error),
+	set component.TelemetrySettings,
+) (*router[I], error) {
+	resourceParser, err := ottlresource.NewParser(ottlfuncs.StandardConverters[*ottlresource.TransformContext](), set)
+	if err != nil {
+		return nil, err
+	}
+	var rt route[I]
+	if err := rt.parseCondition(cfg.Context, cfg.Condition, itemContext, resourceParser.ParseCondition, parseItem); err != nil {
+		return nil, err
+	}
+	return &router[I]{routes: []route[I]{rt}}, nil
+}
+
+// newTracesPriority parses the priority condition of the traces signal.
+func newTracesPriority(cfg *PriorityConfig, set component.TelemetrySettings) (*router[*ottlspan.TransformContext], error) {
+	parser, err := ottlspan.NewParser(ottlfuncs.StandardConverters[*ottlspan.TransformContext](), set)
+	if err != nil {
+		return nil, err
+	}
+	return newPriorityRouter(cfg, routeContextSpan, parser.ParseCondition, set)
+}
+
+// newMetricsPriority parses the priority condition of the metrics signal.
+func newMetricsPriority(cfg *PriorityConfig, set component.TelemetrySettings) (*router[*ottldatapoint.TransformContext], error) {
+	parser, err := ottldatapoint.NewParser(ottlfuncs.StandardConverters[*ottldatapoint.TransformContext](), set)
+	if err != nil {
+		return nil, err
+	}
+	return newPriorityRouter(cfg, routeContextDatapoint, parser.ParseCondition, set)
+}
+
+// newLogsPriority parses the priority condition of the logs signal.
+func newLogsPriority(cfg *PriorityConfig, set component.TelemetrySettings) (*router[*ottllog.TransformContext], error) {
+	parser, err := ottllog.NewParser(ottlfuncs.StandardConverters[*ottllog.TransformContext](), set)
+	if err != nil {
+		return nil, err
+	}
+	return newPriorityRouter(cfg, routeContextLog, parser.ParseCondition, set)
+}
@@ -0,0 +1,113 @@
+package natsexporter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/mikluko/otelnats-collector/internal/metadata"
+)
+
+func newTestLogsRateLimiter(t *testing.T, cfg *RateLimitConfig) *rateLimiter[plog.Logs] {
+	t.Helper()
+	set := componenttest.NewNopTelemetrySettings()
+	tb, err := metadata.NewTelemetryBuilder(set)
+	require.NoError(t, err)
+	l, err := newLogsRateLimiter(cfg, set, tb)
+	require.NoError(t, err)
+	return l
+}
+
+func TestRateLimiter_Reject(t *testing.T) {
+	l := newTestLogsRateLimiter(t, &RateLimitConfig{MessagesPerSecond: 1, Overflow: overflowReject})
+	ld := testLogsWithSeverities(plog.SeverityNumberInfo)
+
+	ok, _, err := l.admit(context.Background(), 10, ld)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, _, err = l.admit(context.Background(), 10, ld)
+	assert.False(t, ok)
+	require.ErrorIs(t, err, errRateLimited)
+	assert.False(t, consumererror.IsPermanent(err))
+}
+
+func TestRateLimiter_Block(t *testing.T) {
+	l := newTestLogsRateLimiter(t, &RateLimitConfig{BytesPerSecond: 1000})
+	ld := testLogsWithSeverities(plog.SeverityNumberInfo)
+
+	// A message larger than the bucket empties it
+	ok, _, err := l.admit(context.Background(), 5000, ld)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	start := time.Now()
+	ok, _, err = l.admit(context.Background(), 100, ld)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	// Waiting ends with the context
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ok, _, err = l.admit(ctx, 1000, ld)
+	assert.False(t, ok)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRateLimiter_Shed(t *testing.T) {
+	l := newTestLogsRateLimiter(t, &RateLimitConfig{
+		MessagesPerSecond: 1,
+		Overflow:          overflowShed,
+		Priority:          &PriorityConfig{Context: "log", Condition: "severity_number >= SEVERITY_NUMBER_WARN"},
+	})
+	ld := testLogsWithSeverities(plog.SeverityNumberInfo, plog.SeverityNumberError, plog.SeverityNumberDebug)
+
+	ok, _, err := l.admit(context.Background(), 10, ld)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Over the limit, only the priority records remain
+	ok, rest, err := l.admit(context.Background(), 10, ld)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	require.Equal(t, 1, rest.LogRecordCount())
+	assert.Equal(t, plog.SeverityNumberError, rest.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).SeverityNumber())
+	assert.Equal(t, 3, ld.LogRecordCount(), "input must not be modified")
+
+	// Priority records are not shed but wait for tokens
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ok, _, err = l.admit(ctx, 10, rest)
+	assert.False(t, ok)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRateLimiter_ShedWithoutPriority(t *testing.T) {
+	l := newTestLogsRateLimiter(t, &RateLimitConfig{MessagesPerSecond: 1, Overflow: overflowShed})
+	ld := testLogsWithSeverities(plog.SeverityNumberError)
+
+	ok, _, err := l.admit(context.Background(), 10, ld)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, rest, err := l.admit(context.Background(), 10, ld)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 0, rest.LogRecordCount())
+}
+
+func testLogsWithSeverities(severities ...plog.SeverityNumber) plog.Logs {
+	ld := plog.NewLogs()
+	records := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	for _, s := range severities {
+		records.AppendEmpty().SetSeverityNumber(s)
+	}
+	return ld
+}
@@ -0,0 +1,112 @@
+package natsexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mikluko/otelnats"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"go.uber.org/zap"
+
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+)
+
+// itemNames names the items counted by an OTLP partial success per signal.
+var itemNames = map[string]string{
+	otelnats.SignalTraces:  "spans",
+	otelnats.SignalMetrics: "data points",
+	otelnats.SignalLogs:    "log records",
+}
+
+// requestPublisher sends each message as a NATS request and waits for the
+// receiver's reply, so a batch is only successful once a receiver consumed it.
+type requestPublisher struct {
+	conn    *nats.Conn
+	timeout time.Duration
+	logger  *zap.Logger
+}
+
+func (p *requestPublisher) publish(ctx context.Context, msg *nats.Msg) error {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	reply, err := p.conn.RequestMsgWithContext(ctx, msg)
+	if err != nil {
+		if errors.Is(err, nats.ErrMaxPayload) || errors.Is(err, nats.ErrBadSubject) {
+			return consumererror.NewPermanent(err)
+		}
+		// No responders and timeouts are retryable: a receiver may come back
+		return fmt.Errorf("request on %q failed: %w", msg.Subject, err)
+	}
+	return p.handleReply(msg, reply)
+}
+
+// handleReply maps the receiver's reply onto the collector's retry semantics.
+// A failed export is retryable unless the receiver marked it permanent; items
+// rejected through OTLP partial success must not be retried.
+func (p *requestPublisher) handleReply(msg, reply *nats.Msg) error {
+	signal := msg.Header.Get(otelnats.HeaderOtelSignal)
+
+	if errMsg := reply.Header.Get(internalnats.HeaderExportError); errMsg != "" {
+		err := fmt.Errorf("receiver failed to export %s: %s", signal, errMsg)
+		if reply.Header.Get(internalnats.HeaderExportErrorPermanent) == "true" {
+			return consumererror.NewPermanent(err)
+		}
+		return err
+	}
+
+	rejected, errMsg, err := partialSuccess(signal, reply.Header.Get(otelnats.HeaderContentType), reply.Data)
+	if err != nil {
+		// The receiver did consume the batch; retrying would duplicate it
+		return consumererror.NewPermanent(fmt.Errorf("invalid reply to %s request: %w", signal, err))
+	}
+	if rejected > 0 {
+		return consumererror.NewPermanent(fmt.Errorf("receiver rejected %d %s: %s", rejected, itemNames[signal], errMsg))
+	}
+	if errMsg != "" {
+		p.logger.Warn("receiver reported partial success",
+			zap.String("subject", msg.Subject),
+			zap.String("message", errMsg),
+		)
+	}
+	return nil
+}
+
+// partialSuccess decodes the partial success of an OTLP export response.
+func partialSuccess(signal, contentType string, data []byte) (rejected int64, errMsg string, err error) {
+	unmarshal := func(unmarshalProto, unmarshalJSON func([]byte) error) error {
+		if contentType == otelnats.ContentTypeJSON {
+			return unmarshalJSON(data)
+		}
+		return unmarshalProto(data)
+	}
+
+	switch signal {
+	case otelnats.SignalTraces:
+		resp := ptraceotlp.NewExportResponse()
+		if err := unmarshal(resp.UnmarshalProto, resp.UnmarshalJSON); err != nil {
+			return 0, "", err
+		}
+		return resp.PartialSuccess().RejectedSpans(), resp.PartialSuccess().ErrorMessage(), nil
+	case otelnats.SignalMetrics:
+		resp := pmetricotlp.NewExportResponse()
+		if err := unmarshal(resp.UnmarshalProto, resp.UnmarshalJSON); err != nil {
+			return 0, "", err
+		}
+		return resp.PartialSuccess().RejectedDataPoints(), resp.PartialSuccess().ErrorMessage(), nil
+	case otelnats.SignalLogs:
+		resp := plogotlp.NewExportResponse()
+		if err := unmarshal(resp.UnmarshalProto, resp.UnmarshalJSON); err != nil {
+			return 0, "", err
+		}
+		return resp.PartialSuccess().RejectedLogRecords(), resp.PartialSuccess().ErrorMessage(), nil
+	default:
+		return 0, "", fmt.Errorf("unknown signal %q", signal)
+	}
+}
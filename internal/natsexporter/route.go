@@ -0,0 +1,442 @@
+package natsexporter
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottldatapoint"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottllog"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlresource"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/contexts/ottlspan"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/pkg/ottl/ottlfuncs"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// OTTL contexts a route condition can be evaluated in.
+const (
+	routeContextResource  = "resource"
+	routeContextSpan      = "span"
+	routeContextLog       = "log"
+	routeContextDatapoint = "datapoint"
+)
+
+// route is a parsed route. Exactly one of resource and item is set,
+// depending on the context the condition is evaluated in.
+type route[I any] struct {
+	resource *ottl.Condition[*ottlresource.TransformContext]
+	item     *ottl.Condition[I]
+	subject  *subjectTemplate
+}
+
+// router assigns each span, data point or log record to the first route whose
+// condition matches it, or to the default subject.
+type router[I interface{ Close() }] struct {
+	routes   []route[I]
+	fallback *subjectTemplate
+}
+
+// newRouter parses the routes of a signal. itemContext is the record-level
+// context of the signal; parseItem parses conditions in that context.
+func newRouter[I interface{ Close() }](
+	cfg SignalConfig,
+	signal, itemContext string,
+	parseItem func(string) (*ottl.Condition[I], error),
+	fallback *subjectTemplate,
+	set component.TelemetrySettings,
+) (*router[I], error) {
+	resourceParser, err := ottlresource.NewParser(ottlfuncs.StandardConverters[*ottlresource.TransformContext](), set)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &router[I]{fallback: fallback}
+	for i, rc := range cfg.Routes {
+		var rt route[I]
+		if err := validateSubjectTemplate(rc.Subject, cfg.SubjectFallback, signal); err != nil {
+			return nil, fmt.Errorf("routes[%d].subject: %w", i, err)
+		}
+		if rt.subject, err = parseSubjectTemplate(rc.Subject, cfg.SubjectFallback, signal); err != nil {
+			return nil, fmt.Errorf("routes[%d].subject: %w", i, err)
+		}
+		if err := rt.parseCondition(rc.Context, rc.Condition, itemContext, resourceParser.ParseCondition, parseItem); err != nil {
+			return nil, fmt.Errorf("routes[%d].%w", i, err)
+		}
+		r.routes = append(r.routes, rt)
+	}
+	return r, nil
+}
+
+// parseCondition parses condition in the named context into rt.
+func (rt *route[I]) parseCondition(
+	context, condition, itemContext string,
+	parseResource func(string) (*ottl.Condition[*ottlresource.TransformContext], error),
+	parseItem func(string) (*ottl.Condition[I], error),
+) error {
+	var err error
+	switch context {
+	case "", routeContextResource:
+		rt.resource, err = parseResource(condition)
+	case itemContext:
+		rt.item, err = parseItem(condition)
+	default:
+		return fmt.Errorf("context must be %s or %s", routeContextResource, itemContext)
+	}
+	if err != nil {
+		return fmt.Errorf("condition: %w", err)
+	}
+	return nil
+}
+
+// subject returns the subject template of destination d.
+func (r *router[I]) subject(d int) *subjectTemplate {
+	if d == len(r.routes) {
+		return r.fallback
+	}
+	return r.routes[d].subject
+}
+
+// resourceMatches evaluates the resource-level routes against a resource.
+func (r *router[I]) resourceMatches(ctx context.Context, resource pcommon.Resource, schema interface {
+	SchemaUrl() string
+	SetSchemaUrl(string)
+},
+) ([]bool, error) {
+	matches := make([]bool, len(r.routes))
+	var tCtx *ottlresource.TransformContext
+	for i, rt := range r.routes {
+		if rt.resource == nil {
+			continue
+		}
+		if tCtx == nil {
+			tCtx = ottlresource.NewTransformContextPtr(resource, schema)
+			defer tCtx.Close()
+		}
+		ok, err := rt.resource.Eval(ctx, tCtx)
+		if err != nil {
+			return nil, fmt.Errorf("route %d: %w", i, err)
+		}
+		matches[i] = ok
+	}
+	return matches, nil
+}
+
+// destination returns the index of the first route matching an item, or
+// len(routes) if none does. resource holds the resource-level matches;
+// newItem creates the item's transform context when it is first needed.
+func (r *router[I]) destination(ctx context.Context, resource []bool, newItem func() I) (int, error) {
+	var tCtx I
+	created := false
+	for i, rt := range r.routes {
+		if rt.item == nil {
+			if resource[i] {
+				return i, nil
+			}
+			continue
+		}
+		if !created {
+			tCtx, created = newItem(), true
+			defer tCtx.Close()
+		}
+		ok, err := rt.item.Eval(ctx, tCtx)
+		if err != nil {
+			return 0, fmt.Errorf("route %d: %w", i, err)
+		}
+		if ok {
+			return i, nil
+		}
+	}
+	return len(r.routes), nil
+}
+
+// newTracesRouter parses the routes of the traces signal.
+func newTracesRouter(cfg SignalConfig, fallback *subjectTemplate, set component.TelemetrySettings) (*router[*ottlspan.TransformContext], error) {
+	parser, err := ottlspan.NewParser(ottlfuncs.StandardConverters[*ottlspan.TransformContext](), set)
+	if err != nil {
+		return nil, err
+	}
+	return newRouter(cfg, "traces", routeContextSpan, parser.ParseCondition, fallback, set)
+}
+
+// newMetricsRouter parses the routes of the metrics signal.
+func newMetricsRouter(cfg SignalConfig, fallback *subjectTemplate, set component.TelemetrySettings) (*router[*ottldatapoint.TransformContext], error) {
+	parser, err := ottldatapoint.NewParser(ottlfuncs.StandardConverters[*ottldatapoint.TransformContext](), set)
+	if err != nil {
+		return nil, err
+	}
+	return newRouter(cfg, "metrics", routeContextDatapoint, parser.ParseCondition, fallback, set)
+}
+
+// newLogsRouter parses the routes of the logs signal.
+func newLogsRouter(cfg SignalConfig, fallback *subjectTemplate, set component.TelemetrySettings) (*router[*ottllog.TransformContext], error) {
+	parser, err := ottllog.NewParser(ottlfuncs.StandardConverters[*ottllog.TransformContext](), set)
+	if err != nil {
+		return nil, err
+	}
+	return newRouter(cfg, "logs", routeContextLog, parser.ParseCondition, fallback, set)
+}
+
+// destinations returns the distinct destinations in dests in ascending order,
+// and whether there is only one.
+func destinations(dests []int) ([]int, bool) {
+	distinct := slices.Compact(slices.Sorted(slices.Values(dests)))
+	return distinct, len(distinct) == 1
+}
+
+// routeTraces partitions td by destination and each partition by subject.
+// If all spans share a destination, td is not copied.
+func routeTraces(ctx context.Context, r *router[*ottlspan.TransformContext], td ptrace.Traces) ([]subjectBatch[ptrace.Traces], error) {
+	dests, err := traceDestinations(ctx, r, td)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dests) == 0 {
+		return tracesBySubject(td, r.fallback), nil
+	}
+	distinct, single := destinations(dests)
+	var batches []subjectBatch[ptrace.Traces]
+	for _, d := range distinct {
+		data := td
+		if !single {
+			data = selectTraces(td, dests, d)
+		}
+		batches = append(batches, tracesBySubject(data, r.subject(d))...)
+	}
+	return batches, nil
+}
+
+// traceDestinations returns the destination of each span of td in traversal order.
+func traceDestinations(ctx context.Context, r *router[*ottlspan.TransformContext], td ptrace.Traces) ([]int, error) {
+	var dests []int
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		resource, err := r.resourceMatches(ctx, rs.Resource(), rs)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < rs.ScopeSpans().Len(); j++ {
+			ss := rs.ScopeSpans().At(j)
+			for k := 0; k < ss.Spans().Len(); k++ {
+				d, err := r.destination(ctx, resource, func() *ottlspan.TransformContext {
+					return ottlspan.NewTransformContextPtr(rs, ss, ss.Spans().At(k))
+				})
+				if err != nil {
+					return nil, err
+				}
+				dests = append(dests, d)
+			}
+		}
+	}
+	return dests, nil
+}
+
+// routeMetrics partitions md by destination and each partition by subject.
+// See routeTraces.
+func routeMetrics(ctx context.Context, r *router[*ottldatapoint.TransformContext], md pmetric.Metrics) ([]subjectBatch[pmetric.Metrics], error) {
+	dests, err := metricDestinations(ctx, r, md)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dests) == 0 {
+		return metricsBySubject(md, r.fallback), nil
+	}
+	distinct, single := destinations(dests)
+	var batches []subjectBatch[pmetric.Metrics]
+	for _, d := range distinct {
+		data := md
+		if !single {
+			data = selectMetrics(md, dests, d)
+		}
+		batches = append(batches, metricsBySubject(data, r.subject(d))...)
+	}
+	return batches, nil
+}
+
+// metricDestinations returns the destination of each data point of md in traversal order.
+func metricDestinations(ctx context.Context, r *router[*ottldatapoint.TransformContext], md pmetric.Metrics) ([]int, error) {
+	var dests []int
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		resource, err := r.resourceMatches(ctx, rm.Resource(), rm)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < rm.ScopeMetrics().Len(); j++ {
+			sm := rm.ScopeMetrics().At(j)
+			for k := 0; k < sm.Metrics().Len(); k++ {
+				m := sm.Metrics().At(k)
+				for _, dp := range dataPoints(m) {
+					d, err := r.destination(ctx, resource, func() *ottldatapoint.TransformContext {
+						return ottldatapoint.NewTransformContextPtr(rm, sm, m, dp)
+					})
+					if err != nil {
+						return nil, err
+					}
+					dests = append(dests, d)
+				}
+			}
+		}
+	}
+	return dests, nil
+}
+
+// routeLogs partitions ld by destination and each partition by subject.
+// See routeTraces.
+func routeLogs(ctx context.Context, r *router[*ottllog.TransformContext], ld plog.Logs) ([]subjectBatch[plog.Logs], error) {
+	dests, err := logDestinations(ctx, r, ld)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dests) == 0 {
+		return logsBySubject(ld, r.fallback), nil
+	}
+	distinct, single := destinations(dests)
+	var batches []subjectBatch[plog.Logs]
+	for _, d := range distinct {
+		data := ld
+		if !single {
+			data = selectLogs(ld, dests, d)
+		}
+		batches = append(batches, logsBySubject(data, r.subject(d))...)
+	}
+	return batches, nil
+}
+
+// logDestinations returns the destination of each log record of ld in traversal order.
+func logDestinations(ctx context.Context, r *router[*ottllog.TransformContext], ld plog.Logs) ([]int, error) {
+	var dests []int
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		resource, err := r.resourceMatches(ctx, rl.Resource(), rl)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < rl.ScopeLogs().Len(); j++ {
+			sl := rl.ScopeLogs().At(j)
+			for k := 0; k < sl.LogRecords().Len(); k++ {
+				d, err := r.destination(ctx, resource, func() *ottllog.TransformContext {
+					return ottllog.NewTransformContextPtr(rl, sl, sl.LogRecords().At(k))
+				})
+				if err != nil {
+					return nil, err
+				}
+				dests = append(dests, d)
+			}
+		}
+	}
+	return dests, nil
+}
+
+// selectTraces returns a copy of td holding only the spans whose entry in
+// groups, indexed in traversal order, is g. Empty scopes and resources are
+// dropped.
+func selectTraces(td ptrace.Traces, groups []int, g int) ptrace.Traces {
+	data := ptrace.NewTraces()
+	td.CopyTo(data)
+	n := 0
+	data.ResourceSpans().RemoveIf(func(rs ptrace.ResourceSpans) bool {
+		rs.ScopeSpans().RemoveIf(func(ss ptrace.ScopeSpans) bool {
+			ss.Spans().RemoveIf(func(ptrace.Span) bool { n++; return groups[n-1] != g })
+			return ss.Spans().Len() == 0
+		})
+		return rs.ScopeSpans().Len() == 0
+	})
+	return data
+}
+
+// selectMetrics is selectTraces for data points. Metrics whose data points
+// all belong to other groups are dropped; metrics without data points are
+// kept in every group.
+func selectMetrics(md pmetric.Metrics, groups []int, g int) pmetric.Metrics {
+	data := pmetric.NewMetrics()
+	md.CopyTo(data)
+	n := 0
+	keep := func() bool { n++; return groups[n-1] == g }
+	data.ResourceMetrics().RemoveIf(func(rm pmetric.ResourceMetrics) bool {
+		rm.ScopeMetrics().RemoveIf(func(sm pmetric.ScopeMetrics) bool {
+			sm.Metrics().RemoveIf(func(m pmetric.Metric) bool {
+				return retainDataPoints(m, keep)
+			})
+			return sm.Metrics().Len() == 0
+		})
+		return rm.ScopeMetrics().Len() == 0
+	})
+	return data
+}
+
+// selectLogs is selectTraces for log records.
+func selectLogs(ld plog.Logs, groups []int, g int) plog.Logs {
+	data := plog.NewLogs()
+	ld.CopyTo(data)
+	n := 0
+	data.ResourceLogs().RemoveIf(func(rl plog.ResourceLogs) bool {
+		rl.ScopeLogs().RemoveIf(func(sl plog.ScopeLogs) bool {
+			sl.LogRecords().RemoveIf(func(plog.LogRecord) bool { n++; return groups[n-1] != g })
+			return sl.LogRecords().Len() == 0
+		})
+		return rl.ScopeLogs().Len() == 0
+	})
+	return data
+}
+
+// dataPoints returns the data points of m as expected by the OTTL datapoint context.
+func dataPoints(m pmetric.Metric) []any {
+	var dps []any
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		for _, dp := range m.Gauge().DataPoints().All() {
+			dps = append(dps, dp)
+		}
+	case pmetric.MetricTypeSum:
+		for _, dp := range m.Sum().DataPoints().All() {
+			dps = append(dps, dp)
+		}
+	case pmetric.MetricTypeHistogram:
+		for _, dp := range m.Histogram().DataPoints().All() {
+			dps = append(dps, dp)
+		}
+	case pmetric.MetricTypeExponentialHistogram:
+		for _, dp := range m.ExponentialHistogram().DataPoints().All() {
+			dps = append(dps, dp)
+		}
+	case pmetric.MetricTypeSummary:
+		for _, dp := range m.Summary().DataPoints().All() {
+			dps = append(dps, dp)
+		}
+	}
+	return dps
+}
+
+// retainDataPoints removes the data points of m for which keep, called in
+// order, returns false. It reports whether m had data points and none is left.
+func retainDataPoints(m pmetric.Metric, keep func() bool) bool {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		return retain(m.Gauge().DataPoints(), keep)
+	case pmetric.MetricTypeSum:
+		return retain(m.Sum().DataPoints(), keep)
+	case pmetric.MetricTypeHistogram:
+		return retain(m.Histogram().DataPoints(), keep)
+	case pmetric.MetricTypeExponentialHistogram:
+		return retain(m.ExponentialHistogram().DataPoints(), keep)
+	case pmetric.MetricTypeSummary:
+		return retain(m.Summary().DataPoints(), keep)
+	default:
+		return false
+	}
+}
+
+// retain is retainDataPoints for a data point slice.
+func retain[P any](dps removable[P], keep func() bool) bool {
+	n := dps.Len()
+	dps.RemoveIf(func(P) bool { return !keep() })
+	return n > 0 && dps.Len() == 0
+}
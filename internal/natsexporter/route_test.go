@@ -0,0 +1,162 @@
+package natsexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestRouteTraces(t *testing.T) {
+	cfg := SignalConfig{
+		Subject: "otel.traces",
+		Routes: []RouteConfig{
+			{Context: "span", Condition: "status.code == STATUS_CODE_ERROR", Subject: "otel.traces.errors"},
+			{Condition: `attributes["service.name"] == "checkout"`, Subject: "otel.traces.checkout"},
+		},
+	}
+	r, err := newTracesRouter(cfg, mustParseSubject(t, cfg.Subject, "traces"), componenttest.NewNopTelemetrySettings())
+	require.NoError(t, err)
+
+	td := ptrace.NewTraces()
+	for _, service := range []string{"api", "checkout"} {
+		rs := td.ResourceSpans().AppendEmpty()
+		rs.Resource().Attributes().PutStr("service.name", service)
+		spans := rs.ScopeSpans().AppendEmpty().Spans()
+		spans.AppendEmpty().SetName(service + "-ok")
+		failed := spans.AppendEmpty()
+		failed.SetName(service + "-failed")
+		failed.Status().SetCode(ptrace.StatusCodeError)
+	}
+
+	batches, err := routeTraces(context.Background(), r, td)
+	require.NoError(t, err)
+	names := map[string][]string{}
+	for _, b := range batches {
+		for _, rs := range b.data.ResourceSpans().All() {
+			for _, ss := range rs.ScopeSpans().All() {
+				for _, span := range ss.Spans().All() {
+					names[b.subject] = append(names[b.subject], span.Name())
+				}
+			}
+		}
+	}
+	// Span routes come first, so failed checkout spans go to errors
+	assert.Equal(t, map[string][]string{
+		"otel.traces.errors":   {"api-failed", "checkout-failed"},
+		"otel.traces.checkout": {"checkout-ok"},
+		"otel.traces":          {"api-ok"},
+	}, names)
+}
+
+func TestRouteMetrics(t *testing.T) {
+	cfg := SignalConfig{
+		Subject: "otel.metrics",
+		Routes: []RouteConfig{
+			{Context: "datapoint", Condition: `attributes["tier"] == "gold"`, Subject: "otel.metrics.gold"},
+		},
+	}
+	r, err := newMetricsRouter(cfg, mustParseSubject(t, cfg.Subject, "metrics"), componenttest.NewNopTelemetrySettings())
+	require.NoError(t, err)
+
+	md := pmetric.NewMetrics()
+	metrics := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics()
+	sum := metrics.AppendEmpty()
+	sum.SetName("requests")
+	sum.SetEmptySum()
+	for _, tier := range []string{"gold", "silver"} {
+		sum.Sum().DataPoints().AppendEmpty().Attributes().PutStr("tier", tier)
+	}
+	gauge := metrics.AppendEmpty()
+	gauge.SetName("queue")
+	gauge.SetEmptyGauge().DataPoints().AppendEmpty().Attributes().PutStr("tier", "silver")
+	metrics.AppendEmpty().SetName("pending")
+	metrics.AppendEmpty().SetName("errors")
+	metrics.At(3).SetEmptySum()
+
+	batches, err := routeMetrics(context.Background(), r, md)
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+
+	names := func(md pmetric.Metrics) []string {
+		var names []string
+		for _, m := range md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().All() {
+			names = append(names, m.Name())
+		}
+		return names
+	}
+
+	gold := batches[0]
+	assert.Equal(t, "otel.metrics.gold", gold.subject)
+	require.Equal(t, 1, gold.data.DataPointCount())
+	// Metrics without matching data points are dropped, those without any are kept
+	assert.Equal(t, []string{"requests", "pending", "errors"}, names(gold.data))
+
+	assert.Equal(t, "otel.metrics", batches[1].subject)
+	assert.Equal(t, 2, batches[1].data.DataPointCount())
+	assert.Equal(t, []string{"requests", "queue", "pending", "errors"}, names(batches[1].data))
+}
+
+func TestRouteLogs(t *testing.T) {
+	cfg := SignalConfig{
+		Subject: "otel.logs",
+		Routes: []RouteConfig{
+			{Context: "log", Condition: "severity_number >= SEVERITY_NUMBER_ERROR", Subject: "otel.logs.errors"},
+		},
+	}
+	r, err := newLogsRouter(cfg, mustParseSubject(t, cfg.Subject, "logs"), componenttest.NewNopTelemetrySettings())
+	require.NoError(t, err)
+
+	// A batch with a single destination is published as is
+	ld := plog.NewLogs()
+	records := ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().SetSeverityNumber(plog.SeverityNumberInfo)
+	records.AppendEmpty().SetSeverityNumber(plog.SeverityNumberDebug)
+	batches, err := routeLogs(context.Background(), r, ld)
+	require.NoError(t, err)
+	require.Len(t, batches, 1)
+	assert.Equal(t, "otel.logs", batches[0].subject)
+	assert.Equal(t, 2, batches[0].data.LogRecordCount())
+
+	records.AppendEmpty().SetSeverityNumber(plog.SeverityNumberFatal)
+	batches, err = routeLogs(context.Background(), r, ld)
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+	assert.Equal(t, "otel.logs.errors", batches[0].subject)
+	assert.Equal(t, 1, batches[0].data.LogRecordCount())
+	assert.Equal(t, "otel.logs", batches[1].subject)
+	assert.Equal(t, 2, batches[1].data.LogRecordCount())
+	assert.Equal(t, 3, ld.LogRecordCount(), "input must not be modified")
+}
+
+func TestNewRouter_Errors(t *testing.T) {
+	set := componenttest.NewNopTelemetrySettings()
+
+	_, err := newLogsRouter(SignalConfig{Routes: []RouteConfig{
+		{Context: "span", Condition: "true", Subject: "otel.logs.spans"},
+	}}, nil, set)
+	assert.EqualError(t, err, "routes[0].context must be resource or log")
+
+	_, err = newLogsRouter(SignalConfig{Routes: []RouteConfig{
+		{Condition: "true", Subject: "otel.logs.all"},
+		{Context: "log", Condition: "severity_number >=", Subject: "otel.logs.errors"},
+	}}, nil, set)
+	assert.ErrorContains(t, err, "routes[1].condition: ")
+
+	_, err = newLogsRouter(SignalConfig{Routes: []RouteConfig{
+		{Condition: "true", Subject: "otel.logs.*"},
+	}}, nil, set)
+	assert.ErrorContains(t, err, "routes[0].subject: ")
+}
+
+func mustParseSubject(t *testing.T, subject, signal string) *subjectTemplate {
+	t.Helper()
+	tmpl, err := parseSubjectTemplate(subject, "", signal)
+	require.NoError(t, err)
+	return tmpl
+}
@@ -0,0 +1,150 @@
+package natsexporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+// seriesHashLen is the number of hex characters of the series hash in a
+// snapshot subject.
+const seriesHashLen = 16
+
+// snapshotMetrics splits md into one batch per data point, published to the
+// series subject <prefix>.<metric name>.<series hash>. Only the latest data
+// point of each series in md is kept, so that a stream keeping one message
+// per subject always holds the current value.
+func snapshotMetrics(md pmetric.Metrics, prefix string) []subjectBatch[pmetric.Metrics] {
+	var batches []subjectBatch[pmetric.Metrics]
+	index := map[string]int{}
+	for _, rm := range md.ResourceMetrics().All() {
+		for _, sm := range rm.ScopeMetrics().All() {
+			for _, m := range sm.Metrics().All() {
+				for i := range dataPoints(m) {
+					point := newSnapshotPoint(rm, sm, m, i)
+					pm := point.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+					attrs, ts := pointAttributes(pm)
+					subject := prefix + "." + metricNameTokens(m.Name()) + "." + seriesHash(rm.Resource().Attributes(), sm.Scope(), attrs)
+
+					n, ok := index[subject]
+					if !ok {
+						index[subject] = len(batches)
+						batches = append(batches, subjectBatch[pmetric.Metrics]{subject: subject, data: point})
+						continue
+					}
+					prev := batches[n].data.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+					if _, prevTS := pointAttributes(prev); ts >= prevTS {
+						batches[n].data = point
+					}
+				}
+			}
+		}
+	}
+	return batches
+}
+
+// newSnapshotPoint returns a copy of the i-th data point of m together with
+// its resource, scope and metric metadata.
+func newSnapshotPoint(rm pmetric.ResourceMetrics, sm pmetric.ScopeMetrics, m pmetric.Metric, i int) pmetric.Metrics {
+	md := pmetric.NewMetrics()
+	rmOut := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().CopyTo(rmOut.Resource())
+	rmOut.SetSchemaUrl(rm.SchemaUrl())
+	smOut := rmOut.ScopeMetrics().AppendEmpty()
+	sm.Scope().CopyTo(smOut.Scope())
+	smOut.SetSchemaUrl(sm.SchemaUrl())
+	mOut := smOut.Metrics().AppendEmpty()
+	mOut.SetName(m.Name())
+	mOut.SetDescription(m.Description())
+	mOut.SetUnit(m.Unit())
+	m.Metadata().CopyTo(mOut.Metadata())
+
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		m.Gauge().DataPoints().At(i).CopyTo(mOut.SetEmptyGauge().DataPoints().AppendEmpty())
+	case pmetric.MetricTypeSum:
+		sum := mOut.SetEmptySum()
+		sum.SetAggregationTemporality(m.Sum().AggregationTemporality())
+		sum.SetIsMonotonic(m.Sum().IsMonotonic())
+		m.Sum().DataPoints().At(i).CopyTo(sum.DataPoints().AppendEmpty())
+	case pmetric.MetricTypeHistogram:
+		hist := mOut.SetEmptyHistogram()
+		hist.SetAggregationTemporality(m.Histogram().AggregationTemporality())
+		m.Histogram().DataPoints().At(i).CopyTo(hist.DataPoints().AppendEmpty())
+	case pmetric.MetricTypeExponentialHistogram:
+		hist := mOut.SetEmptyExponentialHistogram()
+		hist.SetAggregationTemporality(m.ExponentialHistogram().AggregationTemporality())
+		m.ExponentialHistogram().DataPoints().At(i).CopyTo(hist.DataPoints().AppendEmpty())
+	case pmetric.MetricTypeSummary:
+		m.Summary().DataPoints().At(i).CopyTo(mOut.SetEmptySummary().DataPoints().AppendEmpty())
+	}
+	return md
+}
+
+// pointAttributes returns the attributes and timestamp of the single data
+// point of m.
+func pointAttributes(m pmetric.Metric) (pcommon.Map, pcommon.Timestamp) {
+	switch m.Type() {
+	case pmetric.MetricTypeGauge:
+		dp := m.Gauge().DataPoints().At(0)
+		return dp.Attributes(), dp.Timestamp()
+	case pmetric.MetricTypeSum:
+		dp := m.Sum().DataPoints().At(0)
+		return dp.Attributes(), dp.Timestamp()
+	case pmetric.MetricTypeHistogram:
+		dp := m.Histogram().DataPoints().At(0)
+		return dp.Attributes(), dp.Timestamp()
+	case pmetric.MetricTypeExponentialHistogram:
+		dp := m.ExponentialHistogram().DataPoints().At(0)
+		return dp.Attributes(), dp.Timestamp()
+	default:
+		dp := m.Summary().DataPoints().At(0)
+		return dp.Attributes(), dp.Timestamp()
+	}
+}
+
+// metricNameTokens maps a metric name onto subject tokens, keeping its dots
+// so that consumers can filter on name prefixes (e.g. http.server.>).
+func metricNameTokens(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = sanitizeToken(part)
+		if parts[i] == "" {
+			parts[i] = "_"
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// seriesHash identifies a series by its resource attributes, instrumentation
+// scope and data point attributes, independent of attribute order.
+func seriesHash(resource pcommon.Map, scope pcommon.InstrumentationScope, point pcommon.Map) string {
+	h := sha256.New()
+	writeAttrs := func(attrs pcommon.Map) {
+		keys := make([]string, 0, attrs.Len())
+		for k := range attrs.All() {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			v, _ := attrs.Get(k)
+			h.Write([]byte(k))
+			h.Write([]byte{0})
+			h.Write([]byte(v.AsString()))
+			h.Write([]byte{0})
+		}
+		h.Write([]byte{1})
+	}
+	writeAttrs(resource)
+	h.Write([]byte(scope.Name()))
+	h.Write([]byte{0})
+	h.Write([]byte(scope.Version()))
+	h.Write([]byte{0})
+	writeAttrs(scope.Attributes())
+	writeAttrs(point)
+	return hex.EncodeToString(h.Sum(nil))[:seriesHashLen]
+}
@@ -0,0 +1,91 @@
+package natsexporter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestSnapshotMetrics(t *testing.T) {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("service.name", "api")
+	m := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("http.server.requests")
+	m.SetUnit("1")
+	sum := m.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	sum.SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	for _, p := range []struct {
+		route string
+		ts    pcommon.Timestamp
+		value int64
+	}{
+		{"/users", 2, 20},
+		{"/orders", 1, 5},
+		{"/users", 1, 10}, // older than the first /users point
+	} {
+		dp := sum.DataPoints().AppendEmpty()
+		dp.Attributes().PutStr("route", p.route)
+		dp.SetTimestamp(p.ts)
+		dp.SetIntValue(p.value)
+	}
+
+	batches := snapshotMetrics(md, "otel.snapshot")
+	require.Len(t, batches, 2)
+	for _, b := range batches {
+		assert.True(t, strings.HasPrefix(b.subject, "otel.snapshot.http.server.requests."), b.subject)
+		assert.Len(t, b.subject, len("otel.snapshot.http.server.requests.")+seriesHashLen)
+		require.Equal(t, 1, b.data.DataPointCount())
+
+		got := b.data.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+		assert.Equal(t, "1", got.Unit())
+		assert.True(t, got.Sum().IsMonotonic())
+		assert.Equal(t, pmetric.AggregationTemporalityCumulative, got.Sum().AggregationTemporality())
+	}
+	users := batches[0].data.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Sum().DataPoints().At(0)
+	assert.Equal(t, int64(20), users.IntValue(), "latest point of a series wins")
+
+	// The same series from another scope has its own subject
+	sm := rm.ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName("otelhttp")
+	m.CopyTo(sm.Metrics().AppendEmpty())
+	assert.Len(t, snapshotMetrics(md, "otel.snapshot"), 4)
+}
+
+func TestSeriesHash(t *testing.T) {
+	a := pcommon.NewMap()
+	a.PutStr("host", "a")
+	a.PutStr("region", "eu")
+	b := pcommon.NewMap()
+	b.PutStr("region", "eu")
+	b.PutStr("host", "a")
+	empty := pcommon.NewMap()
+	scope := pcommon.NewInstrumentationScope()
+
+	assert.Equal(t, seriesHash(a, scope, empty), seriesHash(b, scope, empty), "attribute order must not matter")
+	assert.NotEqual(t, seriesHash(a, scope, empty), seriesHash(empty, scope, a), "resource and point attributes are distinct")
+
+	// Series of different scopes are distinct
+	other := pcommon.NewInstrumentationScope()
+	other.SetName("otelhttp")
+	assert.NotEqual(t, seriesHash(a, scope, empty), seriesHash(a, other, empty))
+	versioned := pcommon.NewInstrumentationScope()
+	other.CopyTo(versioned)
+	versioned.SetVersion("1.2.0")
+	assert.NotEqual(t, seriesHash(a, other, empty), seriesHash(a, versioned, empty))
+	scoped := pcommon.NewInstrumentationScope()
+	other.CopyTo(scoped)
+	scoped.Attributes().PutStr("host", "a")
+	assert.NotEqual(t, seriesHash(a, other, empty), seriesHash(a, scoped, empty))
+}
+
+func TestMetricNameTokens(t *testing.T) {
+	assert.Equal(t, "http.server.duration", metricNameTokens("http.server.duration"))
+	assert.Equal(t, "process_cpu_.time", metricNameTokens("process cpu*.time"))
+	assert.Equal(t, "a._.b", metricNameTokens("a..b"))
+}
@@ -0,0 +1,118 @@
+package natsexporter
+
+import (
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// removable is implemented by pdata slices.
+type removable[E any] interface {
+	Len() int
+	RemoveIf(func(E) bool)
+}
+
+// halve keeps the first half of left and the second half of right, which must
+// be copies of the same slice. It reports false if there are fewer than two
+// elements to split.
+func halve[E any, S removable[E]](left, right S) bool {
+	n := left.Len()
+	if n < 2 {
+		return false
+	}
+	mid := n / 2
+	i, j := 0, 0
+	left.RemoveIf(func(E) bool { i++; return i > mid })
+	right.RemoveIf(func(E) bool { j++; return j <= mid })
+	return true
+}
+
+// splitTraces splits td into two halves: by resource if there are several,
+// otherwise by scope, otherwise by span. It reports false if td holds a
+// single span and cannot be split further.
+func splitTraces(td ptrace.Traces) (ptrace.Traces, ptrace.Traces, bool) {
+	left, right := ptrace.NewTraces(), ptrace.NewTraces()
+	td.CopyTo(left)
+	td.CopyTo(right)
+
+	lrs, rrs := left.ResourceSpans(), right.ResourceSpans()
+	if lrs.Len() != 1 {
+		return left, right, halve[ptrace.ResourceSpans](lrs, rrs)
+	}
+	lss, rss := lrs.At(0).ScopeSpans(), rrs.At(0).ScopeSpans()
+	if lss.Len() != 1 {
+		return left, right, halve[ptrace.ScopeSpans](lss, rss)
+	}
+	return left, right, halve[ptrace.Span](lss.At(0).Spans(), rss.At(0).Spans())
+}
+
+// splitMetrics splits md into two halves: by resource, scope, metric and
+// finally data point. It reports false if md holds a single data point.
+func splitMetrics(md pmetric.Metrics) (pmetric.Metrics, pmetric.Metrics, bool) {
+	left, right := pmetric.NewMetrics(), pmetric.NewMetrics()
+	md.CopyTo(left)
+	md.CopyTo(right)
+
+	lrm, rrm := left.ResourceMetrics(), right.ResourceMetrics()
+	if lrm.Len() != 1 {
+		return left, right, halve[pmetric.ResourceMetrics](lrm, rrm)
+	}
+	lsm, rsm := lrm.At(0).ScopeMetrics(), rrm.At(0).ScopeMetrics()
+	if lsm.Len() != 1 {
+		return left, right, halve[pmetric.ScopeMetrics](lsm, rsm)
+	}
+	lm, rm := lsm.At(0).Metrics(), rsm.At(0).Metrics()
+	if lm.Len() != 1 {
+		return left, right, halve[pmetric.Metric](lm, rm)
+	}
+
+	l, r := lm.At(0), rm.At(0)
+	switch l.Type() {
+	case pmetric.MetricTypeGauge:
+		return left, right, halve[pmetric.NumberDataPoint](l.Gauge().DataPoints(), r.Gauge().DataPoints())
+	case pmetric.MetricTypeSum:
+		return left, right, halve[pmetric.NumberDataPoint](l.Sum().DataPoints(), r.Sum().DataPoints())
+	case pmetric.MetricTypeHistogram:
+		return left, right, halve[pmetric.HistogramDataPoint](l.Histogram().DataPoints(), r.Histogram().DataPoints())
+	case pmetric.MetricTypeExponentialHistogram:
+		return left, right, halve[pmetric.ExponentialHistogramDataPoint](l.ExponentialHistogram().DataPoints(), r.ExponentialHistogram().DataPoints())
+	case pmetric.MetricTypeSummary:
+		return left, right, halve[pmetric.SummaryDataPoint](l.Summary().DataPoints(), r.Summary().DataPoints())
+	default:
+		return left, right, false
+	}
+}
+
+// splitLogs splits ld into two halves: by resource, scope and then log record.
+// It reports false if ld holds a single log record.
+func splitLogs(ld plog.Logs) (plog.Logs, plog.Logs, bool) {
+	left, right := plog.NewLogs(), plog.NewLogs()
+	ld.CopyTo(left)
+	ld.CopyTo(right)
+
+	lrl, rrl := left.ResourceLogs(), right.ResourceLogs()
+	if lrl.Len() != 1 {
+		return left, right, halve[plog.ResourceLogs](lrl, rrl)
+	}
+	lsl, rsl := lrl.At(0).ScopeLogs(), rrl.At(0).ScopeLogs()
+	if lsl.Len() != 1 {
+		return left, right, halve[plog.ScopeLogs](lsl, rsl)
+	}
+	return left, right, halve[plog.LogRecord](lsl.At(0).LogRecords(), rsl.At(0).LogRecords())
+}
+
+// headerSize returns the size of h as encoded on the wire by nats.go,
+// which counts towards the server's max_payload together with the data.
+func headerSize(h nats.Header) int {
+	if len(h) == 0 {
+		return 0
+	}
+	n := len("NATS/1.0\r\n") + len("\r\n")
+	for k, vs := range h {
+		for _, v := range vs {
+			n += len(k) + len(": ") + len(v) + len("\r\n")
+		}
+	}
+	return n
+}
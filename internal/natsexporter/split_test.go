@@ -0,0 +1,95 @@
+package natsexporter
+
+import (
+	"testing"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestSplitTraces(t *testing.T) {
+	td := ptrace.NewTraces()
+	for i := 0; i < 3; i++ {
+		td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	}
+
+	// Three resources split by resource
+	left, right, ok := splitTraces(td)
+	require.True(t, ok)
+	assert.Equal(t, 1, left.ResourceSpans().Len())
+	assert.Equal(t, 2, right.ResourceSpans().Len())
+
+	// One resource with one scope splits by span, keeping resource and scope
+	td = ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().PutStr("service.name", "api")
+	ss := rs.ScopeSpans().AppendEmpty()
+	ss.Scope().SetName("tracer")
+	for _, name := range []string{"a", "b", "c", "d"} {
+		ss.Spans().AppendEmpty().SetName(name)
+	}
+	left, right, ok = splitTraces(td)
+	require.True(t, ok)
+	for _, half := range []ptrace.Traces{left, right} {
+		require.Equal(t, 2, half.SpanCount())
+		rs := half.ResourceSpans().At(0)
+		v, _ := rs.Resource().Attributes().Get("service.name")
+		assert.Equal(t, "api", v.Str())
+		assert.Equal(t, "tracer", rs.ScopeSpans().At(0).Scope().Name())
+	}
+	assert.Equal(t, "a", left.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name())
+	assert.Equal(t, "c", right.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Name())
+
+	// A single span cannot be split
+	td = ptrace.NewTraces()
+	td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	_, _, ok = splitTraces(td)
+	assert.False(t, ok)
+}
+
+func TestSplitMetrics_DataPoints(t *testing.T) {
+	md := pmetric.NewMetrics()
+	m := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	m.SetName("requests")
+	sum := m.SetEmptySum()
+	sum.SetIsMonotonic(true)
+	for i := 0; i < 5; i++ {
+		sum.DataPoints().AppendEmpty().SetIntValue(int64(i))
+	}
+
+	left, right, ok := splitMetrics(md)
+	require.True(t, ok)
+	assert.Equal(t, 2, left.DataPointCount())
+	assert.Equal(t, 3, right.DataPointCount())
+
+	rm := right.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	assert.Equal(t, "requests", rm.Name())
+	assert.True(t, rm.Sum().IsMonotonic())
+	assert.Equal(t, int64(2), rm.Sum().DataPoints().At(0).IntValue())
+}
+
+func TestSplitLogs_Scopes(t *testing.T) {
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+
+	left, right, ok := splitLogs(ld)
+	require.True(t, ok)
+	assert.Equal(t, 1, left.ResourceLogs().At(0).ScopeLogs().Len())
+	assert.Equal(t, 1, right.ResourceLogs().At(0).ScopeLogs().Len())
+	assert.Equal(t, 2, ld.LogRecordCount(), "input must not be modified")
+}
+
+func TestHeaderSize(t *testing.T) {
+	assert.Equal(t, 0, headerSize(nil))
+
+	h := nats.Header{}
+	h.Set("Content-Type", "application/x-protobuf")
+	// "NATS/1.0\r\n" + "Content-Type: application/x-protobuf\r\n" + "\r\n"
+	assert.Equal(t, 10+38+2, headerSize(h))
+}
@@ -0,0 +1,214 @@
+package natsexporter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+)
+
+const (
+	templateSignal     = "signal"
+	templateAttrPrefix = "attr:"
+)
+
+// tokenRegex matches a valid subject fallback token.
+var tokenRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// subjectTemplate is a parsed SignalConfig.Subject.
+// ${signal} is resolved at parse time; ${attr:key} is resolved per resource.
+type subjectTemplate struct {
+	segments []subjectSegment
+	fallback string
+	dynamic  bool
+}
+
+// subjectSegment is either a literal piece of the subject or a reference
+// to a resource attribute.
+type subjectSegment struct {
+	literal string
+	attr    string
+}
+
+// subjectBatch is the part of a pdata batch destined for a single subject.
+type subjectBatch[T any] struct {
+	subject string
+	data    T
+}
+
+// parseSubjectTemplate parses a subject template for the given signal.
+// An empty fallback defaults to defaultSubjectFallback.
+func parseSubjectTemplate(subject, fallback, signal string) (*subjectTemplate, error) {
+	if fallback == "" {
+		fallback = defaultSubjectFallback
+	}
+	t := &subjectTemplate{fallback: fallback}
+
+	var literal strings.Builder
+	rest := subject
+	for {
+		start := strings.Index(rest, "${")
+		if start < 0 {
+			literal.WriteString(rest)
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, errors.New("unterminated template variable")
+		}
+		literal.WriteString(rest[:start])
+		name := rest[start+2 : start+end]
+		rest = rest[start+end+1:]
+
+		switch {
+		case name == templateSignal:
+			literal.WriteString(signal)
+		case strings.HasPrefix(name, templateAttrPrefix) && len(name) > len(templateAttrPrefix):
+			if literal.Len() > 0 {
+				t.segments = append(t.segments, subjectSegment{literal: literal.String()})
+				literal.Reset()
+			}
+			t.segments = append(t.segments, subjectSegment{attr: strings.TrimPrefix(name, templateAttrPrefix)})
+			t.dynamic = true
+		default:
+			return nil, fmt.Errorf("unknown template variable %q", name)
+		}
+	}
+	if literal.Len() > 0 {
+		t.segments = append(t.segments, subjectSegment{literal: literal.String()})
+	}
+
+	return t, nil
+}
+
+// validateSubjectTemplate checks that a subject template is well-formed and
+// renders to a valid publish subject.
+func validateSubjectTemplate(subject, fallback, signal string) error {
+	if fallback != "" && !tokenRegex.MatchString(fallback) {
+		return errors.New("subject_fallback contains invalid characters")
+	}
+	t, err := parseSubjectTemplate(subject, fallback, signal)
+	if err != nil {
+		return err
+	}
+	return internalnats.ValidatePublishSubject(t.render(pcommon.NewMap()))
+}
+
+// render resolves the template against a set of resource attributes.
+// Attribute values are sanitized into valid subject tokens; missing or
+// empty attributes are replaced with the fallback token.
+func (t *subjectTemplate) render(attrs pcommon.Map) string {
+	if !t.dynamic {
+		if len(t.segments) == 0 {
+			return ""
+		}
+		return t.segments[0].literal
+	}
+
+	var b strings.Builder
+	for _, seg := range t.segments {
+		if seg.attr == "" {
+			b.WriteString(seg.literal)
+			continue
+		}
+		token := ""
+		if v, ok := attrs.Get(seg.attr); ok {
+			token = sanitizeToken(v.AsString())
+		}
+		if token == "" {
+			token = t.fallback
+		}
+		b.WriteString(token)
+	}
+	return b.String()
+}
+
+// sanitizeToken replaces every character that is not valid inside a single
+// NATS subject token (including the '.' separator and wildcards) with '_'.
+func sanitizeToken(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+// tracesBySubject splits td into one batch per rendered subject.
+// Batches are returned in order of first appearance. If the template does not
+// reference resource attributes, td is returned as a single batch without copying.
+func tracesBySubject(td ptrace.Traces, t *subjectTemplate) []subjectBatch[ptrace.Traces] {
+	if !t.dynamic {
+		return []subjectBatch[ptrace.Traces]{{subject: t.render(pcommon.NewMap()), data: td}}
+	}
+
+	var batches []subjectBatch[ptrace.Traces]
+	index := map[string]int{}
+	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		rs := td.ResourceSpans().At(i)
+		subject := t.render(rs.Resource().Attributes())
+		n, ok := index[subject]
+		if !ok {
+			n = len(batches)
+			index[subject] = n
+			batches = append(batches, subjectBatch[ptrace.Traces]{subject: subject, data: ptrace.NewTraces()})
+		}
+		rs.CopyTo(batches[n].data.ResourceSpans().AppendEmpty())
+	}
+	return batches
+}
+
+// metricsBySubject splits md into one batch per rendered subject.
+// See tracesBySubject.
+func metricsBySubject(md pmetric.Metrics, t *subjectTemplate) []subjectBatch[pmetric.Metrics] {
+	if !t.dynamic {
+		return []subjectBatch[pmetric.Metrics]{{subject: t.render(pcommon.NewMap()), data: md}}
+	}
+
+	var batches []subjectBatch[pmetric.Metrics]
+	index := map[string]int{}
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		rm := md.ResourceMetrics().At(i)
+		subject := t.render(rm.Resource().Attributes())
+		n, ok := index[subject]
+		if !ok {
+			n = len(batches)
+			index[subject] = n
+			batches = append(batches, subjectBatch[pmetric.Metrics]{subject: subject, data: pmetric.NewMetrics()})
+		}
+		rm.CopyTo(batches[n].data.ResourceMetrics().AppendEmpty())
+	}
+	return batches
+}
+
+// logsBySubject splits ld into one batch per rendered subject.
+// See tracesBySubject.
+func logsBySubject(ld plog.Logs, t *subjectTemplate) []subjectBatch[plog.Logs] {
+	if !t.dynamic {
+		return []subjectBatch[plog.Logs]{{subject: t.render(pcommon.NewMap()), data: ld}}
+	}
+
+	var batches []subjectBatch[plog.Logs]
+	index := map[string]int{}
+	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		rl := ld.ResourceLogs().At(i)
+		subject := t.render(rl.Resource().Attributes())
+		n, ok := index[subject]
+		if !ok {
+			n = len(batches)
+			index[subject] = n
+			batches = append(batches, subjectBatch[plog.Logs]{subject: subject, data: plog.NewLogs()})
+		}
+		rl.CopyTo(batches[n].data.ResourceLogs().AppendEmpty())
+	}
+	return batches
+}
@@ -0,0 +1,123 @@
+package natsexporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+func TestSubjectTemplate_Render(t *testing.T) {
+	tests := []struct {
+		name     string
+		subject  string
+		fallback string
+		attrs    map[string]any
+		want     string
+	}{
+		{
+			name:    "static subject",
+			subject: "otel.logs",
+			want:    "otel.logs",
+		},
+		{
+			name:    "signal variable",
+			subject: "otel.${signal}.raw",
+			want:    "otel.logs.raw",
+		},
+		{
+			name:    "attribute variables",
+			subject: "otel.${signal}.${attr:k8s.cluster.name}.${attr:k8s.namespace.name}",
+			attrs: map[string]any{
+				"k8s.cluster.name":   "prod-eu",
+				"k8s.namespace.name": "payments",
+			},
+			want: "otel.logs.prod-eu.payments",
+		},
+		{
+			name:    "attribute value is sanitized",
+			subject: "otel.logs.${attr:service.name}",
+			attrs:   map[string]any{"service.name": "api.v2 *edge*>"},
+			want:    "otel.logs.api_v2__edge__",
+		},
+		{
+			name:    "non-string attribute",
+			subject: "otel.logs.${attr:shard}",
+			attrs:   map[string]any{"shard": int64(7)},
+			want:    "otel.logs.7",
+		},
+		{
+			name:    "missing attribute uses default fallback",
+			subject: "otel.logs.${attr:k8s.cluster.name}",
+			want:    "otel.logs.unknown",
+		},
+		{
+			name:     "empty attribute uses configured fallback",
+			subject:  "otel.logs.${attr:k8s.cluster.name}",
+			fallback: "none",
+			attrs:    map[string]any{"k8s.cluster.name": ""},
+			want:     "otel.logs.none",
+		},
+		{
+			name:    "attribute inside token",
+			subject: "otel.logs.tenant-${attr:tenant}",
+			attrs:   map[string]any{"tenant": "acme"},
+			want:    "otel.logs.tenant-acme",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := parseSubjectTemplate(tt.subject, tt.fallback, "logs")
+			require.NoError(t, err)
+
+			attrs := pcommon.NewMap()
+			require.NoError(t, attrs.FromRaw(tt.attrs))
+			assert.Equal(t, tt.want, tmpl.render(attrs))
+		})
+	}
+}
+
+func TestTracesBySubject(t *testing.T) {
+	tmpl, err := parseSubjectTemplate("otel.traces.${attr:cluster}", "", "traces")
+	require.NoError(t, err)
+
+	td := ptrace.NewTraces()
+	for _, cluster := range []string{"a", "b", "a", ""} {
+		rs := td.ResourceSpans().AppendEmpty()
+		if cluster != "" {
+			rs.Resource().Attributes().PutStr("cluster", cluster)
+		}
+		rs.ScopeSpans().AppendEmpty().Spans().AppendEmpty().SetName("span-" + cluster)
+	}
+
+	batches := tracesBySubject(td, tmpl)
+	require.Len(t, batches, 3)
+
+	assert.Equal(t, "otel.traces.a", batches[0].subject)
+	assert.Equal(t, 2, batches[0].data.SpanCount())
+	assert.Equal(t, "otel.traces.b", batches[1].subject)
+	assert.Equal(t, 1, batches[1].data.SpanCount())
+	assert.Equal(t, "otel.traces.unknown", batches[2].subject)
+	assert.Equal(t, 1, batches[2].data.SpanCount())
+
+	// Input must not be modified
+	assert.Equal(t, 4, td.SpanCount())
+}
+
+func TestLogsBySubject_Static(t *testing.T) {
+	tmpl, err := parseSubjectTemplate("otel.${signal}", "", "logs")
+	require.NoError(t, err)
+
+	ld := plog.NewLogs()
+	ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	ld.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+
+	batches := logsBySubject(ld, tmpl)
+	require.Len(t, batches, 1)
+	assert.Equal(t, "otel.logs", batches[0].subject)
+	assert.Equal(t, 2, batches[0].data.LogRecordCount())
+}
@@ -0,0 +1,24 @@
+// Package natsextension provides a NATS connection shared by the NATS
+// receiver and exporter.
+package natsextension
+
+import (
+	"go.opentelemetry.io/collector/component"
+
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+)
+
+// Config defines configuration for the NATS connection extension.
+type Config struct {
+	internalnats.ClientConfig `mapstructure:",squash"`
+}
+
+var _ component.Config = (*Config)(nil)
+
+// Validate checks if the configuration is valid.
+func (c *Config) Validate() error {
+	if err := internalnats.ValidateURL(c.ClientConfig.URL); err != nil {
+		return err
+	}
+	return c.ClientConfig.Auth.Validate()
+}
@@ -0,0 +1,53 @@
+package natsextension
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *Config
+		wantErr string
+	}{
+		{
+			name: "valid config",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{URL: "nats://localhost:4222"},
+			},
+		},
+		{
+			name:    "missing url",
+			cfg:     &Config{},
+			wantErr: "url is required",
+		},
+		{
+			name: "multiple auth methods",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+					Auth: internalnats.AuthConfig{
+						Token:    "secret",
+						NKeyFile: "/path/to/nkey",
+					},
+				},
+			},
+			wantErr: "only one authentication method can be configured",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package natsextension
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+	"go.uber.org/zap"
+
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+)
+
+type natsExtension struct {
+	config   *Config
+	settings extension.Settings
+	logger   *zap.Logger
+
+	conn *nats.Conn
+
+	// stopDeferred stops waiting for a connection established in the background
+	stopDeferred func()
+}
+
+var _ internalnats.ConnectionExtension = (*natsExtension)(nil)
+
+func newNatsExtension(cfg *Config, set extension.Settings) *natsExtension {
+	return &natsExtension{
+		config:   cfg,
+		settings: set,
+		logger:   set.Logger,
+	}
+}
+
+func (e *natsExtension) Start(ctx context.Context, host component.Host) error {
+	// Report connection state changes once on behalf of all components
+	// sharing the connection
+	opts := append(internalnats.StatusOptions(host, e.logger), nats.Name("otel-collector/"+e.settings.ID.String()))
+	conn, err := internalnats.Connect(ctx, e.config.ClientConfig, e.logger, opts...)
+	if err != nil {
+		return err
+	}
+	e.conn = conn
+
+	// Nothing to set up, but report a connection established in the background
+	e.stopDeferred, err = internalnats.StartWhenConnected(ctx, host, conn, e.logger, func(context.Context) error {
+		return nil
+	})
+	return err
+}
+
+func (e *natsExtension) Shutdown(ctx context.Context) error {
+	if e.stopDeferred != nil {
+		e.stopDeferred()
+	}
+	if e.conn == nil {
+		return nil
+	}
+	defer e.conn.Close()
+
+	// Components referencing the extension shut down first;
+	// deliver anything they left buffered
+	if !e.conn.IsConnected() {
+		return nil
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return e.conn.FlushWithContext(ctx)
+	}
+	return e.conn.Flush()
+}
+
+// Conn returns the shared connection.
+func (e *natsExtension) Conn() *nats.Conn {
+	return e.conn
+}
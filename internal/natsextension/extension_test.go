@@ -0,0 +1,53 @@
+package natsextension
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componentstatus"
+	"go.opentelemetry.io/collector/extension/extensiontest"
+
+	"github.com/mikluko/otelnats-collector/internal/metadata"
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+	"github.com/mikluko/otelnats-collector/internal/testutil"
+)
+
+func TestNewFactory(t *testing.T) {
+	factory := NewFactory()
+	require.NotNil(t, factory)
+	assert.Equal(t, metadata.Type, factory.Type())
+	assert.Equal(t, "nats://localhost:4222", factory.CreateDefaultConfig().(*Config).URL)
+}
+
+func TestExtension_Lifecycle(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.URL = ns.ClientURL()
+	cfg.ReconnectWait = 10 * time.Millisecond
+
+	ext, err := factory.Create(ctx, extensiontest.NewNopSettings(metadata.Type), cfg)
+	require.NoError(t, err)
+
+	host := testutil.NewHost(nil)
+	require.NoError(t, ext.Start(ctx, host))
+
+	conn := ext.(internalnats.ConnectionExtension).Conn()
+	require.NotNil(t, conn)
+	assert.True(t, conn.IsConnected())
+
+	// Losing the server is reported once, as a recoverable error
+	ns.Shutdown()
+	require.Eventually(t, func() bool {
+		return len(host.Statuses()) > 0
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, componentstatus.StatusRecoverableError, host.Statuses()[0])
+
+	require.NoError(t, ext.Shutdown(ctx))
+	assert.True(t, conn.IsClosed())
+}
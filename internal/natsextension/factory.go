@@ -0,0 +1,35 @@
+package natsextension
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/extension"
+
+	"github.com/mikluko/otelnats-collector/internal/metadata"
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+)
+
+// NewFactory creates a factory for the NATS connection extension.
+func NewFactory() extension.Factory {
+	return extension.NewFactory(
+		metadata.Type,
+		createDefaultConfig,
+		createExtension,
+		metadata.ExtensionStability,
+	)
+}
+
+func createDefaultConfig() component.Config {
+	return &Config{
+		ClientConfig: internalnats.NewDefaultClientConfig(),
+	}
+}
+
+func createExtension(
+	_ context.Context,
+	set extension.Settings,
+	cfg component.Config,
+) (extension.Extension, error) {
+	return newNatsExtension(cfg.(*Config), set), nil
+}
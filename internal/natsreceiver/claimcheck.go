@@ -0,0 +1,91 @@
+package natsreceiver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+)
+
+// claimCheckResolver fetches payloads referenced by claim-check headers
+// from the JetStream Object Store buckets it is allowed to read.
+type claimCheckResolver struct {
+	js      jetstream.JetStream
+	buckets []string
+	maxSize int // of a stored payload, in bytes
+
+	mu     sync.Mutex
+	stores map[string]jetstream.ObjectStore
+}
+
+func newClaimCheckResolver(js jetstream.JetStream, buckets []string, maxSize int) *claimCheckResolver {
+	return &claimCheckResolver{
+		js:      js,
+		buckets: buckets,
+		maxSize: maxSize,
+		stores:  map[string]jetstream.ObjectStore{},
+	}
+}
+
+// claimCheckReference returns the bucket and object named by the message headers.
+// ok is false if the message carries its payload inline.
+func claimCheckReference(headers nats.Header) (bucket, object string, ok bool) {
+	bucket = headers.Get(internalnats.HeaderClaimCheckBucket)
+	object = headers.Get(internalnats.HeaderClaimCheckObject)
+	return bucket, object, bucket != "" && object != ""
+}
+
+// fetch returns the stored payload of a claim-check reference. References
+// to a bucket not allowed or to an object above maxSize are returned as
+// decodeError, since fetching them again will not help.
+func (c *claimCheckResolver) fetch(ctx context.Context, bucket, object string) ([]byte, error) {
+	if !slices.Contains(c.buckets, bucket) {
+		return nil, decodeError{fmt.Errorf("claim-check bucket %q is not in claim_check.buckets", bucket)}
+	}
+	store, err := c.store(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	info, err := store.GetInfo(ctx, object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch object %q from bucket %q: %w", object, bucket, err)
+	}
+	if info.Size > uint64(c.maxSize) {
+		return nil, decodeError{fmt.Errorf("object %q in bucket %q exceeds %d bytes", object, bucket, c.maxSize)}
+	}
+	data, err := store.GetBytes(ctx, object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch object %q from bucket %q: %w", object, bucket, err)
+	}
+	return data, nil
+}
+
+// delete removes the stored payload of a claim-check reference.
+func (c *claimCheckResolver) delete(ctx context.Context, bucket, object string) error {
+	store, err := c.store(ctx, bucket)
+	if err != nil {
+		return err
+	}
+	return store.Delete(ctx, object)
+}
+
+// store returns the Object Store bound to bucket, binding it on first use.
+func (c *claimCheckResolver) store(ctx context.Context, bucket string) (jetstream.ObjectStore, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if store, ok := c.stores[bucket]; ok {
+		return store, nil
+	}
+	store, err := c.js.ObjectStore(ctx, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to bind object store %q: %w", bucket, err)
+	}
+	c.stores[bucket] = store
+	return store, nil
+}
@@ -0,0 +1,216 @@
+package natsreceiver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mikluko/otelnats"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+)
+
+// coalescedMsg is a message whose data was merged by a coalescer.
+type coalescedMsg struct {
+	otelnats.MessageCore
+
+	// jsMsg is the JetStream message, whose acknowledgement is claimed
+	// until the coalesced data is consumed. Nil for core NATS messages.
+	jsMsg *jetStreamMsg
+}
+
+// coalescer merges the data of consecutive messages of a signal carrying
+// data of type T, and flushes it as a single pipeline call once it holds
+// maxMessages messages or timeout after the first one arrived.
+type coalescer[T any] struct {
+	maxMessages int
+	timeout     time.Duration
+	newData     func() T
+	merge       func(dst, src T) // moves src into dst
+	flush       func(data T, format string, msgs []coalescedMsg)
+
+	mu     sync.Mutex
+	data   T
+	format string // content type of the first message
+	msgs   []coalescedMsg
+	gen    uint64 // incremented by take, so that stale timers do nothing
+	closed bool
+	timers sync.WaitGroup // flushes started by timers
+}
+
+func newCoalescer[T any](cfg *CoalesceConfig, newData func() T, merge func(dst, src T), flush func(T, string, []coalescedMsg)) *coalescer[T] {
+	c := &coalescer[T]{
+		maxMessages: cfg.MaxMessages,
+		timeout:     cfg.Timeout,
+		newData:     newData,
+		merge:       merge,
+		flush:       flush,
+	}
+	if c.maxMessages == 0 {
+		c.maxMessages = defaultCoalesceMaxMessages
+	}
+	if c.timeout == 0 {
+		c.timeout = defaultCoalesceTimeout
+	}
+	return c
+}
+
+// add merges the data of msg, flushing right away if this fills the batch.
+func (c *coalescer[T]) add(msg coalescedMsg, format string, data T) {
+	c.mu.Lock()
+	if len(c.msgs) == 0 {
+		c.data = c.newData()
+		c.format = format
+		gen := c.gen
+		time.AfterFunc(c.timeout, func() { c.flushExpired(gen) })
+	}
+	c.merge(c.data, data)
+	c.msgs = append(c.msgs, msg)
+	if len(c.msgs) < c.maxMessages {
+		c.mu.Unlock()
+		return
+	}
+	data, format, msgs := c.take()
+	c.mu.Unlock()
+	c.flush(data, format, msgs)
+}
+
+// flushExpired flushes the batch started in generation gen, unless it was
+// flushed already.
+func (c *coalescer[T]) flushExpired(gen uint64) {
+	c.mu.Lock()
+	if c.closed || gen != c.gen || len(c.msgs) == 0 {
+		c.mu.Unlock()
+		return
+	}
+	data, format, msgs := c.take()
+	c.timers.Add(1)
+	c.mu.Unlock()
+	defer c.timers.Done()
+	c.flush(data, format, msgs)
+}
+
+// take removes the current batch. The caller must hold c.mu.
+func (c *coalescer[T]) take() (T, string, []coalescedMsg) {
+	data, format, msgs := c.data, c.format, c.msgs
+	var zero T
+	c.data, c.format, c.msgs = zero, "", nil
+	c.gen++
+	return data, format, msgs
+}
+
+// shutdown flushes the current batch and waits for flushes started by
+// timers. Messages must no longer be added.
+func (c *coalescer[T]) shutdown() {
+	c.mu.Lock()
+	c.closed = true
+	var msgs []coalescedMsg
+	var data T
+	var format string
+	if len(c.msgs) > 0 {
+		data, format, msgs = c.take()
+	}
+	c.mu.Unlock()
+	if len(msgs) > 0 {
+		c.flush(data, format, msgs)
+	}
+	c.timers.Wait()
+}
+
+// coalesced claims the acknowledgement of msg, if it is a JetStream
+// message, until its coalesced data is consumed.
+func (r *natsReceiver) coalesced(msg otelnats.MessageCore) coalescedMsg {
+	m, ok := jetStreamMsgOf(msg)
+	if !ok {
+		return coalescedMsg{MessageCore: msg}
+	}
+	m.claim()
+	return coalescedMsg{MessageCore: msg, jsMsg: m}
+}
+
+// consumeCoalesced calls consume, the pipeline call for the coalesced data
+// of msgs holding items spans, data points or log records, and acknowledges
+// all messages together according to its result.
+func (r *natsReceiver) consumeCoalesced(ctx context.Context, cfg *SignalConfig, msgs []coalescedMsg, items int, consume func(context.Context) error) error {
+	var jsMsgs []*jetStreamMsg
+	for _, m := range msgs {
+		if m.jsMsg != nil {
+			jsMsgs = append(jsMsgs, m.jsMsg)
+		}
+	}
+
+	consumeCtx, stop := r.keepAllInProgress(ctx, cfg.JetStream, jsMsgs)
+	err := consume(consumeCtx)
+	stop()
+
+	if err != nil {
+		r.handleError(receiverError{
+			err:    err,
+			items:  items,
+			fields: []zap.Field{zap.String("subject", cfg.Subject), zap.Int("messages", len(msgs))},
+		})
+		for _, m := range jsMsgs {
+			if ackErr := m.settle(m.receiver.failureAck(m, err)); ackErr != nil {
+				r.handleError(ackErr)
+			}
+		}
+		return err
+	}
+	for _, m := range msgs {
+		r.releaseClaimCheck(ctx, m)
+		if m.jsMsg != nil {
+			if ackErr := m.jsMsg.settle(jetstream.Msg.Ack); ackErr != nil {
+				r.handleError(ackErr)
+			}
+		}
+	}
+	return nil
+}
+
+// newTracesCoalescer returns the coalescer of the traces signal.
+func (r *natsReceiver) newTracesCoalescer(cfg *SignalConfig) *coalescer[ptrace.Traces] {
+	merge := func(dst, src ptrace.Traces) {
+		src.ResourceSpans().MoveAndAppendTo(dst.ResourceSpans())
+	}
+	return newCoalescer(cfg.Coalesce, ptrace.NewTraces, merge, func(td ptrace.Traces, format string, msgs []coalescedMsg) {
+		ctx := r.obsrecv.StartTracesOp(context.Background())
+		spanCount := td.SpanCount()
+		err := r.consumeCoalesced(ctx, cfg, msgs, spanCount, func(ctx context.Context) error {
+			return r.tracesConsumer.ConsumeTraces(ctx, td)
+		})
+		r.obsrecv.EndTracesOp(ctx, format, spanCount, err)
+	})
+}
+
+// newMetricsCoalescer returns the coalescer of the metrics signal.
+func (r *natsReceiver) newMetricsCoalescer(cfg *SignalConfig) *coalescer[pmetric.Metrics] {
+	merge := func(dst, src pmetric.Metrics) {
+		src.ResourceMetrics().MoveAndAppendTo(dst.ResourceMetrics())
+	}
+	return newCoalescer(cfg.Coalesce, pmetric.NewMetrics, merge, func(md pmetric.Metrics, format string, msgs []coalescedMsg) {
+		ctx := r.obsrecv.StartMetricsOp(context.Background())
+		dataPointCount := md.DataPointCount()
+		err := r.consumeCoalesced(ctx, cfg, msgs, dataPointCount, func(ctx context.Context) error {
+			return r.metricsConsumer.ConsumeMetrics(ctx, md)
+		})
+		r.obsrecv.EndMetricsOp(ctx, format, dataPointCount, err)
+	})
+}
+
+// newLogsCoalescer returns the coalescer of the logs signal.
+func (r *natsReceiver) newLogsCoalescer(cfg *SignalConfig) *coalescer[plog.Logs] {
+	merge := func(dst, src plog.Logs) {
+		src.ResourceLogs().MoveAndAppendTo(dst.ResourceLogs())
+	}
+	return newCoalescer(cfg.Coalesce, plog.NewLogs, merge, func(ld plog.Logs, format string, msgs []coalescedMsg) {
+		ctx := r.obsrecv.StartLogsOp(context.Background())
+		logCount := ld.LogRecordCount()
+		err := r.consumeCoalesced(ctx, cfg, msgs, logCount, func(ctx context.Context) error {
+			return r.logsConsumer.ConsumeLogs(ctx, ld)
+		})
+		r.obsrecv.EndLogsOp(ctx, format, logCount, err)
+	})
+}
@@ -3,8 +3,11 @@ package natsreceiver
 
 import (
 	"errors"
+	"regexp"
+	"strconv"
 	"time"
 
+	"github.com/nats-io/nats.go/jetstream"
 	"go.opentelemetry.io/collector/component"
 
 	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
@@ -14,12 +17,31 @@ import (
 type Config struct {
 	internalnats.ClientConfig `mapstructure:",squash"`
 
+	// Connection references a nats extension (e.g. nats/main) whose
+	// connection is shared instead of opening a dedicated one.
+	// If set, the client settings above are ignored.
+	Connection *component.ID `mapstructure:"connection,omitempty"`
+
 	// QueueGroup for load-balanced consumption across receivers.
 	// When multiple receivers use the same queue group, each message is
 	// delivered to only one receiver in the group.
 	// Only applies to core NATS mode (JetStream uses durable consumers).
 	QueueGroup string `mapstructure:"queue_group"`
 
+	// MaxDecompressedSize caps the size in bytes of a decompressed payload
+	// for messages carrying a Content-Encoding header (default: 64 MiB).
+	// Messages exceeding the cap are rejected to protect against decompression bombs.
+	MaxDecompressedSize int `mapstructure:"max_decompressed_size"`
+
+	// IncludeMetadata propagates message headers as client metadata, so that
+	// processors and exporters downstream can use them (e.g. headers set by an
+	// exporter's from_client_metadata).
+	IncludeMetadata bool `mapstructure:"include_metadata"`
+
+	// ClaimCheck configures handling of claim-check messages, whose payload
+	// is stored in a JetStream Object Store bucket.
+	ClaimCheck ClaimCheckConfig `mapstructure:"claim_check"`
+
 	// Traces configuration.
 	Traces SignalConfig `mapstructure:"traces"`
 
@@ -30,6 +52,20 @@ type Config struct {
 	Logs SignalConfig `mapstructure:"logs"`
 }
 
+// ClaimCheckConfig holds claim-check configuration.
+type ClaimCheckConfig struct {
+	// Buckets lists the Object Store buckets claim-check references may
+	// point to. References to any other bucket are rejected, so that
+	// producers cannot make the receiver read arbitrary buckets.
+	Buckets []string `mapstructure:"buckets"`
+
+	// Delete removes the object once its payload was consumed successfully.
+	// Only enable when each message is consumed by a single receiver
+	// (queue group or shared JetStream consumer); otherwise other receivers
+	// may find the object gone.
+	Delete bool `mapstructure:"delete"`
+}
+
 // SignalConfig holds signal-specific receiver configuration.
 type SignalConfig struct {
 	// Subject is the NATS subject to subscribe to.
@@ -45,9 +81,78 @@ type SignalConfig struct {
 	// Currently only otlp_proto is supported.
 	Encoding string `mapstructure:"encoding"`
 
+	// RequestReply answers each message that carries a reply subject with an
+	// OTLP export response once the pipeline consumed it, for exporters using
+	// request_reply delivery. Only applies to core NATS mode.
+	RequestReply bool `mapstructure:"request_reply"`
+
 	// JetStream configuration for at-least-once delivery guarantees.
 	// If not set, uses core NATS (at-most-once delivery).
 	JetStream *JetStreamConfig `mapstructure:"jetstream,omitempty"`
+
+	// Snapshot reads the current value of every series published by an
+	// exporter in snapshot mode, then switches to live updates. Each start
+	// consumes <subject>.> through a new ephemeral consumer delivering the
+	// last message per subject. Metrics with JetStream only.
+	Snapshot bool `mapstructure:"snapshot"`
+
+	// Partitions splits the partition subjects <subject>.0 to
+	// <subject>.<count-1>, as published by an exporter with partitions,
+	// among the receivers sharing the same bucket.
+	Partitions *PartitionsConfig `mapstructure:"partitions,omitempty"`
+
+	// DeadLetter republishes poison messages to a dead letter subject and
+	// terminates them instead of having them redelivered. JetStream only.
+	DeadLetter *DeadLetterConfig `mapstructure:"dead_letter,omitempty"`
+
+	// Coalesce merges the data of consecutive messages into a single
+	// pipeline call, which cuts the per-call overhead of many small messages.
+	Coalesce *CoalesceConfig `mapstructure:"coalesce,omitempty"`
+}
+
+// CoalesceConfig holds coalescing configuration. With JetStream, the
+// messages behind a pipeline call are acknowledged together once it returns,
+// or all settled by the redelivery settings if it fails.
+type CoalesceConfig struct {
+	// MaxMessages is the number of messages whose data is merged into a
+	// single pipeline call (default: 100).
+	MaxMessages int `mapstructure:"max_messages,omitempty"`
+
+	// Timeout after the first message of a batch at which its data is
+	// consumed even if fewer messages arrived (default: 200ms). With
+	// JetStream, it must be less than ack_wait.
+	Timeout time.Duration `mapstructure:"timeout,omitempty"`
+}
+
+// DeadLetterConfig holds dead letter configuration. Dead-lettered messages
+// keep their payload and headers, plus Otel-Dead-Letter-Error,
+// Otel-Dead-Letter-Subject and Otel-Dead-Letter-Deliveries headers.
+type DeadLetterConfig struct {
+	// Subject the poison messages are republished to.
+	Subject string `mapstructure:"subject"`
+
+	// Stream, if set, publishes through JetStream and expects the subject
+	// to be stored by this stream, so that a message is only terminated
+	// once its copy is persisted. Otherwise core NATS publish is used.
+	Stream string `mapstructure:"stream,omitempty"`
+}
+
+// PartitionsConfig holds partition assignment configuration. Receivers
+// register in a NATS KV bucket and each partition is assigned to exactly
+// one live receiver by rendezvous hashing. With JetStream, each partition
+// is consumed through its own durable consumer, <consumer>-<partition>.
+type PartitionsConfig struct {
+	// Count is the number of partitions. It must match the exporter.
+	Count int `mapstructure:"count"`
+
+	// Bucket is the KV bucket receivers register in. It is created with
+	// TTL as its TTL if missing.
+	Bucket string `mapstructure:"bucket"`
+
+	// TTL after which a receiver that stopped heartbeating is considered
+	// gone and its partitions are reassigned (default: 15s). Receivers
+	// heartbeat and rebalance every TTL/3.
+	TTL time.Duration `mapstructure:"ttl,omitempty"`
 }
 
 // JetStreamConfig holds JetStream-specific receiver configuration.
@@ -81,18 +186,110 @@ type JetStreamConfig struct {
 	// RateBurst is the token bucket capacity (maximum burst size).
 	// Required when RateLimit is set. Also used as the default fetch batch size.
 	RateBurst int `mapstructure:"rate_burst,omitempty"`
+
+	// DeliverPolicy is where a consumer created by the receiver starts
+	// reading the stream. Requires Consumer to be set.
+	//   all - the first message in the stream (default)
+	//   new - messages published after the consumer was created
+	//   last - the last message in the stream
+	//   last_per_subject - the last message of every subject
+	//   by_start_sequence - the message with sequence OptStartSeq
+	//   by_start_time - the first message published at or after OptStartTime
+	DeliverPolicy string `mapstructure:"deliver_policy,omitempty"`
+
+	// OptStartSeq is the stream sequence to start at with by_start_sequence.
+	OptStartSeq uint64 `mapstructure:"opt_start_seq,omitempty"`
+
+	// OptStartTime is the RFC 3339 time to start at with by_start_time.
+	OptStartTime time.Time `mapstructure:"opt_start_time,omitempty"`
+
+	// ReplayPolicy is the rate at which stored messages are delivered:
+	// instant, as fast as possible (default), or original, at the rate they
+	// were published. Requires Consumer to be set.
+	ReplayPolicy string `mapstructure:"replay_policy,omitempty"`
+
+	// Provision creates or verifies the durable consumer at start.
+	// Requires Consumer to be set.
+	Provision *ConsumerProvisionConfig `mapstructure:"provision,omitempty"`
+
+	// MaxDeliver terminates a message the pipeline failed to consume on its
+	// max_deliver-th delivery, or dead-letters it if dead_letter is set.
+	// 0 redelivers without limit (default).
+	MaxDeliver int `mapstructure:"max_deliver,omitempty"`
+
+	// Backoff delays the redelivery of messages negatively acknowledged by
+	// the nak policy: the n-th delay follows the n-th failed delivery, and
+	// the last one all later failures. If empty, redelivery is immediate.
+	Backoff []time.Duration `mapstructure:"backoff,omitempty"`
+
+	// OnRetryableError decides what happens to a message the pipeline failed
+	// to consume with a retryable error:
+	//   nak - negatively acknowledge it for redelivery after backoff (default)
+	//   timeout - leave it unacknowledged to be redelivered after ack_wait
+	//   term - terminate it so that it is never redelivered
+	OnRetryableError string `mapstructure:"on_retryable_error,omitempty"`
+
+	// OnPermanentError is the same for permanent errors (default: nak).
+	OnPermanentError string `mapstructure:"on_permanent_error,omitempty"`
+
+	// InProgress keeps a message that the pipeline is still consuming from
+	// being redelivered once ack_wait expires.
+	InProgress *InProgressConfig `mapstructure:"in_progress,omitempty"`
+}
+
+// InProgressConfig holds in-progress acknowledgement configuration.
+type InProgressConfig struct {
+	// Interval between in-progress acknowledgements of a message, each of
+	// which restarts its ack_wait (default: a third of the consumer's ack_wait).
+	Interval time.Duration `mapstructure:"interval,omitempty"`
+
+	// MaxDuration after which the receiver gives up on a message still being
+	// consumed: it is negatively acknowledged and the pipeline call canceled.
+	// 0 never gives up.
+	MaxDuration time.Duration `mapstructure:"max_duration,omitempty"`
+}
+
+// ConsumerProvisionConfig describes the durable consumer to provision.
+// AckWait is taken from the enclosing JetStream configuration.
+type ConsumerProvisionConfig struct {
+	// Policy controls how an existing consumer is treated (default: create_only):
+	//   create_only - create if missing, log drift of an existing consumer
+	//   reconcile - create if missing, update an existing consumer to match
+	//   verify_only - never mutate; a missing consumer or any drift fails start
+	Policy string `mapstructure:"policy,omitempty"`
+
+	// DeliverPolicy for a new consumer: all (default), last, new or last_per_subject.
+	//
+	// Deprecated: use deliver_policy of the enclosing JetStream configuration.
+	DeliverPolicy string `mapstructure:"deliver_policy,omitempty"`
+
+	// FilterSubjects restricts the consumer to these subjects.
+	// Defaults to the signal subject.
+	FilterSubjects []string `mapstructure:"filter_subjects,omitempty"`
 }
 
 var _ component.Config = (*Config)(nil)
 
 // Validate checks if the configuration is valid.
 func (c *Config) Validate() error {
-	if err := internalnats.ValidateURL(c.ClientConfig.URL); err != nil {
-		return err
+	// Client settings only apply to a dedicated connection
+	if c.Connection == nil {
+		if err := internalnats.ValidateURL(c.ClientConfig.URL); err != nil {
+			return err
+		}
+		if err := c.ClientConfig.Auth.Validate(); err != nil {
+			return err
+		}
 	}
 
-	if err := c.ClientConfig.Auth.Validate(); err != nil {
-		return err
+	if c.MaxDecompressedSize < 0 {
+		return errors.New("max_decompressed_size must be non-negative")
+	}
+
+	for _, bucket := range c.ClaimCheck.Buckets {
+		if !bucketRegex.MatchString(bucket) {
+			return errors.New("claim_check.buckets: " + strconv.Quote(bucket) + " contains invalid characters")
+		}
 	}
 
 	// At least one signal must be configured with a subject
@@ -120,8 +317,38 @@ func (c *Config) Validate() error {
 			return errors.New("only otlp_proto encoding is currently supported")
 		}
 
+		if cfg.Snapshot {
+			if err := validateSnapshot(cfg, name); err != nil {
+				return errors.New(name + ".snapshot: " + err.Error())
+			}
+		}
+
+		if cfg.Partitions != nil {
+			if err := validatePartitions(cfg); err != nil {
+				return errors.New(name + ".partitions: " + err.Error())
+			}
+		}
+
+		if cfg.DeadLetter != nil {
+			if err := validateDeadLetter(cfg); err != nil {
+				return errors.New(name + ".dead_letter: " + err.Error())
+			}
+		}
+
+		if cfg.Coalesce != nil {
+			if c.IncludeMetadata {
+				return errors.New(name + ".coalesce cannot be combined with include_metadata")
+			}
+			if err := validateCoalesce(cfg); err != nil {
+				return errors.New(name + ".coalesce: " + err.Error())
+			}
+		}
+
 		// Validate JetStream configuration if enabled for this signal
 		if cfg.JetStream != nil {
+			if cfg.RequestReply {
+				return errors.New(name + ".request_reply and jetstream are mutually exclusive")
+			}
 			if cfg.JetStream.Stream == "" {
 				return errors.New(name + ".jetstream.stream is required when jetstream is enabled")
 			}
@@ -140,8 +367,206 @@ func (c *Config) Validate() error {
 			if cfg.JetStream.RateBurst < 0 {
 				return errors.New(name + ".jetstream.rate_burst must be non-negative")
 			}
+			if err := validateRedelivery(cfg); err != nil {
+				return errors.New(name + ".jetstream." + err.Error())
+			}
+			if p := cfg.JetStream.Provision; p != nil {
+				if cfg.JetStream.Consumer == "" {
+					return errors.New(name + ".jetstream.consumer is required when provision is set")
+				}
+				if err := internalnats.ValidateProvisionPolicy(p.Policy); err != nil {
+					return errors.New(name + ".jetstream.provision: " + err.Error())
+				}
+				if _, err := internalnats.ParseDeliverPolicy(p.DeliverPolicy); err != nil {
+					return errors.New(name + ".jetstream.provision: " + err.Error())
+				}
+				for _, subject := range p.FilterSubjects {
+					if err := internalnats.ValidateSubject(subject); err != nil {
+						return errors.New(name + ".jetstream.provision.filter_subjects: " + err.Error())
+					}
+				}
+			}
+			if err := validateStartPosition(cfg); err != nil {
+				return errors.New(name + ".jetstream." + err.Error())
+			}
 		}
 	}
 
 	return nil
 }
+
+// validateSnapshot checks the snapshot settings of a signal.
+func validateSnapshot(cfg SignalConfig, name string) error {
+	if name != "metrics" {
+		return errors.New("only supported for metrics")
+	}
+	if cfg.JetStream == nil {
+		return errors.New("requires jetstream")
+	}
+	if cfg.JetStream.Consumer != "" || cfg.JetStream.Provision != nil {
+		return errors.New("uses an ephemeral consumer; jetstream.consumer and provision cannot be set")
+	}
+	if cfg.JetStream.DeliverPolicy != "" {
+		return errors.New("delivers the last message per subject; jetstream.deliver_policy cannot be set")
+	}
+	if cfg.Partitions != nil {
+		return errors.New("cannot be combined with partitions")
+	}
+	if err := internalnats.ValidatePublishSubject(cfg.Subject); err != nil {
+		return errors.New("subject must not contain wildcards")
+	}
+	return nil
+}
+
+// validateDeadLetter checks the dead letter settings of a signal.
+func validateDeadLetter(cfg SignalConfig) error {
+	dl := cfg.DeadLetter
+	if cfg.JetStream == nil {
+		return errors.New("requires jetstream")
+	}
+	if cfg.JetStream.Consumer == "" && !cfg.Snapshot {
+		return errors.New("requires jetstream.consumer")
+	}
+	if dl.Subject == "" {
+		return errors.New("subject is required")
+	}
+	if err := internalnats.ValidatePublishSubject(dl.Subject); err != nil {
+		return errors.New("subject: " + err.Error())
+	}
+	return nil
+}
+
+// validateCoalesce checks the coalescing settings of a signal.
+func validateCoalesce(cfg SignalConfig) error {
+	co := cfg.Coalesce
+	if co.MaxMessages < 0 {
+		return errors.New("max_messages must be non-negative")
+	}
+	if co.Timeout < 0 {
+		return errors.New("timeout must be non-negative")
+	}
+	if cfg.RequestReply {
+		return errors.New("cannot be combined with request_reply")
+	}
+	if js := cfg.JetStream; js != nil {
+		if js.Consumer == "" && !cfg.Snapshot {
+			return errors.New("requires jetstream.consumer")
+		}
+		ackWait := js.AckWait
+		if ackWait == 0 {
+			ackWait = defaultAckWait
+		}
+		timeout := co.Timeout
+		if timeout == 0 {
+			timeout = defaultCoalesceTimeout
+		}
+		if timeout >= ackWait {
+			return errors.New("timeout must be less than jetstream.ack_wait")
+		}
+	}
+	return nil
+}
+
+// validateRedelivery checks the redelivery and in-progress settings of a
+// JetStream signal.
+func validateRedelivery(cfg SignalConfig) error {
+	js := cfg.JetStream
+	if js.MaxDeliver < 0 {
+		return errors.New("max_deliver must be non-negative")
+	}
+	for _, d := range js.Backoff {
+		if d <= 0 {
+			return errors.New("backoff delays must be positive")
+		}
+	}
+	if err := validateFailurePolicy(js.OnRetryableError); err != nil {
+		return errors.New("on_retryable_error " + err.Error())
+	}
+	if err := validateFailurePolicy(js.OnPermanentError); err != nil {
+		return errors.New("on_permanent_error " + err.Error())
+	}
+	if p := js.InProgress; p != nil {
+		if p.Interval < 0 || p.MaxDuration < 0 {
+			return errors.New("in_progress durations must be non-negative")
+		}
+		if js.AckWait > 0 && p.Interval >= js.AckWait {
+			return errors.New("in_progress.interval must be less than ack_wait")
+		}
+	}
+	if (js.configuresRedelivery() || js.InProgress != nil) && js.Consumer == "" && !cfg.Snapshot {
+		return errors.New("consumer is required to configure redelivery or in_progress")
+	}
+	return nil
+}
+
+// validateStartPosition checks the deliver and replay policies of a
+// JetStream signal.
+func validateStartPosition(cfg SignalConfig) error {
+	js := cfg.JetStream
+	if js.DeliverPolicy != "" && js.Provision != nil && js.Provision.DeliverPolicy != "" {
+		return errors.New("deliver_policy and provision.deliver_policy are mutually exclusive")
+	}
+	policy, err := internalnats.ParseDeliverPolicy(js.deliverPolicy())
+	if err != nil {
+		return err
+	}
+	if _, err := internalnats.ParseReplayPolicy(js.ReplayPolicy); err != nil {
+		return err
+	}
+	switch {
+	case policy == jetstream.DeliverByStartSequencePolicy && js.OptStartSeq == 0:
+		return errors.New("opt_start_seq is required when deliver_policy is by_start_sequence")
+	case policy != jetstream.DeliverByStartSequencePolicy && js.OptStartSeq != 0:
+		return errors.New("opt_start_seq requires deliver_policy by_start_sequence")
+	case policy == jetstream.DeliverByStartTimePolicy && js.OptStartTime.IsZero():
+		return errors.New("opt_start_time is required when deliver_policy is by_start_time")
+	case policy != jetstream.DeliverByStartTimePolicy && !js.OptStartTime.IsZero():
+		return errors.New("opt_start_time requires deliver_policy by_start_time")
+	}
+	if (js.DeliverPolicy != "" || js.ReplayPolicy != "") && js.Consumer == "" && !cfg.Snapshot {
+		return errors.New("consumer is required to configure deliver_policy or replay_policy")
+	}
+	return nil
+}
+
+// validateFailurePolicy checks that policy is a known failure policy.
+func validateFailurePolicy(policy string) error {
+	switch policy {
+	case "", failureNak, failureTimeout, failureTerm:
+		return nil
+	default:
+		return errors.New("must be nak, timeout or term")
+	}
+}
+
+// bucketRegex matches valid KV and Object Store bucket names.
+var bucketRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// validatePartitions checks the partition settings of a signal.
+func validatePartitions(cfg SignalConfig) error {
+	p := cfg.Partitions
+	if p.Count <= 0 {
+		return errors.New("count must be positive")
+	}
+	if p.Bucket == "" {
+		return errors.New("bucket is required")
+	}
+	if !bucketRegex.MatchString(p.Bucket) {
+		return errors.New("bucket contains invalid characters")
+	}
+	if p.TTL < 0 || (p.TTL > 0 && p.TTL < time.Second) {
+		return errors.New("ttl must be at least 1s")
+	}
+	if err := internalnats.ValidatePublishSubject(cfg.Subject); err != nil {
+		return errors.New("subject must not contain wildcards")
+	}
+	if js := cfg.JetStream; js != nil {
+		if js.Consumer == "" {
+			return errors.New("jetstream.consumer is required")
+		}
+		if js.Provision != nil && len(js.Provision.FilterSubjects) > 0 {
+			return errors.New("jetstream.provision.filter_subjects cannot be set")
+		}
+	}
+	return nil
+}
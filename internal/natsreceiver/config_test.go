@@ -2,9 +2,11 @@ package natsreceiver
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
 
 	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
 )
@@ -164,6 +166,558 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			wantErr: "rate_burst must be non-negative",
 		},
+		{
+			name: "negative max_decompressed_size",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				MaxDecompressedSize: -1,
+				Traces:              SignalConfig{Subject: "otel.traces"},
+			},
+			wantErr: "max_decompressed_size must be non-negative",
+		},
+		{
+			name: "valid claim_check buckets",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				ClaimCheck: ClaimCheckConfig{Buckets: []string{"otel-payloads", "otel_large"}},
+				Traces:     SignalConfig{Subject: "otel.traces"},
+			},
+			wantErr: "",
+		},
+		{
+			name: "invalid claim_check bucket",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				ClaimCheck: ClaimCheckConfig{Buckets: []string{"otel.payloads"}},
+				Traces:     SignalConfig{Subject: "otel.traces"},
+			},
+			wantErr: `claim_check.buckets: "otel.payloads" contains invalid characters`,
+		},
+		{
+			name: "shared connection ignores client settings",
+			cfg: &Config{
+				Connection: func() *component.ID {
+					id := component.MustNewIDWithName("nats", "main")
+					return &id
+				}(),
+				Traces: SignalConfig{Subject: "otel.traces"},
+			},
+			wantErr: "",
+		},
+		{
+			name: "request_reply with jetstream",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:      "otel.logs",
+					RequestReply: true,
+					JetStream:    &JetStreamConfig{Stream: "OTEL"},
+				},
+			},
+			wantErr: "logs.request_reply and jetstream are mutually exclusive",
+		},
+		{
+			name: "jetstream provision",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject: "otel.logs.>",
+					JetStream: &JetStreamConfig{
+						Stream:   "OTEL",
+						Consumer: "otel-collector",
+						Provision: &ConsumerProvisionConfig{
+							Policy:        "verify_only",
+							DeliverPolicy: "new",
+						},
+					},
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name: "jetstream provision without consumer",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject: "otel.logs",
+					JetStream: &JetStreamConfig{
+						Stream:    "OTEL",
+						Provision: &ConsumerProvisionConfig{},
+					},
+				},
+			},
+			wantErr: "logs.jetstream.consumer is required when provision is set",
+		},
+		{
+			name: "jetstream provision invalid deliver_policy",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject: "otel.logs",
+					JetStream: &JetStreamConfig{
+						Stream:    "OTEL",
+						Consumer:  "otel-collector",
+						Provision: &ConsumerProvisionConfig{DeliverPolicy: "oldest"},
+					},
+				},
+			},
+			wantErr: "logs.jetstream.provision: deliver_policy must be all, last, new, last_per_subject, by_start_sequence or by_start_time",
+		},
+		{
+			name: "partitions",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{
+					Subject:    "otel.traces",
+					JetStream:  &JetStreamConfig{Stream: "OTEL", Consumer: "otel-collector"},
+					Partitions: &PartitionsConfig{Count: 16, Bucket: "otel-members", TTL: 10 * time.Second},
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name: "partitions without bucket",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{
+					Subject:    "otel.traces",
+					Partitions: &PartitionsConfig{Count: 16},
+				},
+			},
+			wantErr: "traces.partitions: bucket is required",
+		},
+		{
+			name: "partitions with wildcard subject",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{
+					Subject:    "otel.traces.>",
+					Partitions: &PartitionsConfig{Count: 16, Bucket: "otel-members"},
+				},
+			},
+			wantErr: "traces.partitions: subject must not contain wildcards",
+		},
+		{
+			name: "partitions jetstream without consumer",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{
+					Subject:    "otel.traces",
+					JetStream:  &JetStreamConfig{Stream: "OTEL"},
+					Partitions: &PartitionsConfig{Count: 16, Bucket: "otel-members"},
+				},
+			},
+			wantErr: "traces.partitions: jetstream.consumer is required",
+		},
+		{
+			name: "snapshot",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Metrics: SignalConfig{
+					Subject:   "otel.snapshot",
+					JetStream: &JetStreamConfig{Stream: "OTEL_SNAPSHOT"},
+					Snapshot:  true,
+				},
+			},
+			wantErr: "",
+		},
+		{
+			name: "snapshot without jetstream",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Metrics: SignalConfig{Subject: "otel.snapshot", Snapshot: true},
+			},
+			wantErr: "metrics.snapshot: requires jetstream",
+		},
+		{
+			name: "snapshot with durable consumer",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Metrics: SignalConfig{
+					Subject:   "otel.snapshot",
+					JetStream: &JetStreamConfig{Stream: "OTEL_SNAPSHOT", Consumer: "dashboards"},
+					Snapshot:  true,
+				},
+			},
+			wantErr: "metrics.snapshot: uses an ephemeral consumer; jetstream.consumer and provision cannot be set",
+		},
+		{
+			name: "partitions short ttl",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{
+					Subject:    "otel.traces",
+					Partitions: &PartitionsConfig{Count: 16, Bucket: "otel-members", TTL: time.Millisecond},
+				},
+			},
+			wantErr: "traces.partitions: ttl must be at least 1s",
+		},
+		{
+			name: "jetstream redelivery",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject: "otel.logs",
+					JetStream: &JetStreamConfig{
+						Stream:           "OTEL",
+						Consumer:         "otel-logs",
+						MaxDeliver:       10,
+						Backoff:          []time.Duration{time.Second, 10 * time.Second},
+						OnRetryableError: "nak",
+						OnPermanentError: "term",
+					},
+				},
+			},
+		},
+		{
+			name: "jetstream redelivery invalid policy",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs",
+					JetStream: &JetStreamConfig{Stream: "OTEL", Consumer: "otel-logs", OnPermanentError: "drop"},
+				},
+			},
+			wantErr: "logs.jetstream.on_permanent_error must be nak, timeout or term",
+		},
+		{
+			name: "jetstream redelivery non-positive backoff",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs",
+					JetStream: &JetStreamConfig{Stream: "OTEL", Consumer: "otel-logs", Backoff: []time.Duration{0}},
+				},
+			},
+			wantErr: "logs.jetstream.backoff delays must be positive",
+		},
+		{
+			name: "jetstream redelivery without consumer",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs",
+					JetStream: &JetStreamConfig{Stream: "OTEL", MaxDeliver: 5},
+				},
+			},
+			wantErr: "logs.jetstream.consumer is required to configure redelivery or in_progress",
+		},
+		{
+			name: "jetstream in_progress",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{
+					Subject: "otel.traces",
+					JetStream: &JetStreamConfig{
+						Stream:     "OTEL",
+						Consumer:   "otel-traces",
+						AckWait:    time.Minute,
+						InProgress: &InProgressConfig{Interval: 20 * time.Second, MaxDuration: 10 * time.Minute},
+					},
+				},
+			},
+		},
+		{
+			name: "jetstream in_progress interval not below ack_wait",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Traces: SignalConfig{
+					Subject: "otel.traces",
+					JetStream: &JetStreamConfig{
+						Stream:     "OTEL",
+						Consumer:   "otel-traces",
+						AckWait:    time.Minute,
+						InProgress: &InProgressConfig{Interval: time.Minute},
+					},
+				},
+			},
+			wantErr: "traces.jetstream.in_progress.interval must be less than ack_wait",
+		},
+		{
+			name: "dead letter",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:    "otel.logs",
+					JetStream:  &JetStreamConfig{Stream: "OTEL", Consumer: "otel-logs"},
+					DeadLetter: &DeadLetterConfig{Subject: "otel.dlq.logs", Stream: "OTEL_DLQ"},
+				},
+			},
+		},
+		{
+			name: "dead letter without jetstream",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:    "otel.logs",
+					DeadLetter: &DeadLetterConfig{Subject: "otel.dlq.logs"},
+				},
+			},
+			wantErr: "logs.dead_letter: requires jetstream",
+		},
+		{
+			name: "dead letter without consumer",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:    "otel.logs",
+					JetStream:  &JetStreamConfig{Stream: "OTEL"},
+					DeadLetter: &DeadLetterConfig{Subject: "otel.dlq.logs"},
+				},
+			},
+			wantErr: "logs.dead_letter: requires jetstream.consumer",
+		},
+		{
+			name: "dead letter wildcard subject",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:    "otel.logs",
+					JetStream:  &JetStreamConfig{Stream: "OTEL", Consumer: "otel-logs"},
+					DeadLetter: &DeadLetterConfig{Subject: "otel.dlq.*"},
+				},
+			},
+			wantErr: "logs.dead_letter: subject:",
+		},
+		{
+			name: "coalesce",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs",
+					JetStream: &JetStreamConfig{Stream: "OTEL", Consumer: "otel-logs"},
+					Coalesce:  &CoalesceConfig{MaxMessages: 500, Timeout: time.Second},
+				},
+			},
+		},
+		{
+			name: "coalesce without consumer",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs",
+					JetStream: &JetStreamConfig{Stream: "OTEL"},
+					Coalesce:  &CoalesceConfig{},
+				},
+			},
+			wantErr: "logs.coalesce: requires jetstream.consumer",
+		},
+		{
+			name: "coalesce timeout not below ack_wait",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs",
+					JetStream: &JetStreamConfig{Stream: "OTEL", Consumer: "otel-logs", AckWait: time.Second},
+					Coalesce:  &CoalesceConfig{Timeout: time.Second},
+				},
+			},
+			wantErr: "logs.coalesce: timeout must be less than jetstream.ack_wait",
+		},
+		{
+			name: "coalesce with include_metadata",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				IncludeMetadata: true,
+				Logs: SignalConfig{
+					Subject:  "otel.logs",
+					Coalesce: &CoalesceConfig{},
+				},
+			},
+			wantErr: "logs.coalesce cannot be combined with include_metadata",
+		},
+		{
+			name: "coalesce with request_reply",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:      "otel.logs",
+					RequestReply: true,
+					Coalesce:     &CoalesceConfig{},
+				},
+			},
+			wantErr: "logs.coalesce: cannot be combined with request_reply",
+		},
+		{
+			name: "jetstream start position",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject: "otel.logs",
+					JetStream: &JetStreamConfig{
+						Stream:        "OTEL",
+						Consumer:      "otel-logs",
+						DeliverPolicy: "by_start_time",
+						OptStartTime:  time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC),
+						ReplayPolicy:  "original",
+					},
+				},
+			},
+		},
+		{
+			name: "jetstream invalid deliver_policy",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs",
+					JetStream: &JetStreamConfig{Stream: "OTEL", Consumer: "otel-logs", DeliverPolicy: "oldest"},
+				},
+			},
+			wantErr: "logs.jetstream.deliver_policy must be all, last, new, last_per_subject, by_start_sequence or by_start_time",
+		},
+		{
+			name: "jetstream invalid replay_policy",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs",
+					JetStream: &JetStreamConfig{Stream: "OTEL", Consumer: "otel-logs", ReplayPolicy: "fast"},
+				},
+			},
+			wantErr: "logs.jetstream.replay_policy must be instant or original",
+		},
+		{
+			name: "jetstream by_start_sequence without opt_start_seq",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs",
+					JetStream: &JetStreamConfig{Stream: "OTEL", Consumer: "otel-logs", DeliverPolicy: "by_start_sequence"},
+				},
+			},
+			wantErr: "logs.jetstream.opt_start_seq is required when deliver_policy is by_start_sequence",
+		},
+		{
+			name: "jetstream opt_start_time without by_start_time",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject: "otel.logs",
+					JetStream: &JetStreamConfig{
+						Stream:        "OTEL",
+						Consumer:      "otel-logs",
+						DeliverPolicy: "new",
+						OptStartTime:  time.Date(2026, 10, 16, 6, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			wantErr: "logs.jetstream.opt_start_time requires deliver_policy by_start_time",
+		},
+		{
+			name: "jetstream deliver_policy set twice",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject: "otel.logs",
+					JetStream: &JetStreamConfig{
+						Stream:        "OTEL",
+						Consumer:      "otel-logs",
+						DeliverPolicy: "new",
+						Provision:     &ConsumerProvisionConfig{DeliverPolicy: "new"},
+					},
+				},
+			},
+			wantErr: "logs.jetstream.deliver_policy and provision.deliver_policy are mutually exclusive",
+		},
+		{
+			name: "jetstream deliver_policy without consumer",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Logs: SignalConfig{
+					Subject:   "otel.logs",
+					JetStream: &JetStreamConfig{Stream: "OTEL", DeliverPolicy: "new"},
+				},
+			},
+			wantErr: "logs.jetstream.consumer is required to configure deliver_policy or replay_policy",
+		},
+		{
+			name: "snapshot with deliver_policy",
+			cfg: &Config{
+				ClientConfig: internalnats.ClientConfig{
+					URL: "nats://localhost:4222",
+				},
+				Metrics: SignalConfig{
+					Subject:   "otel.snapshot",
+					Snapshot:  true,
+					JetStream: &JetStreamConfig{Stream: "OTEL_SNAPSHOT", DeliverPolicy: "all"},
+				},
+			},
+			wantErr: "metrics.snapshot: delivers the last message per subject; jetstream.deliver_policy cannot be set",
+		},
 	}
 
 	for _, tt := range tests {
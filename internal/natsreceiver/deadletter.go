@@ -0,0 +1,84 @@
+package natsreceiver
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+
+	"github.com/mikluko/otelnats-collector/internal/metadata"
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+)
+
+// errMaxDeliveries is the dead letter reason of a message the pipeline
+// failed to consume max_deliver times.
+const errMaxDeliveries = "maximum deliveries exceeded"
+
+// deadLetterer republishes poison messages to the dead letter subject and
+// terminates them, rather than have them redelivered over and over.
+type deadLetterer struct {
+	js        jetstream.JetStream
+	config    *DeadLetterConfig
+	telemetry *metadata.TelemetryBuilder
+	logger    *zap.Logger
+}
+
+func newDeadLetterer(js jetstream.JetStream, cfg *DeadLetterConfig, telemetry *metadata.TelemetryBuilder, logger *zap.Logger) *deadLetterer {
+	return &deadLetterer{
+		js:        js,
+		config:    cfg,
+		telemetry: telemetry,
+		logger:    logger,
+	}
+}
+
+// deadLetter republishes msg to the dead letter subject and terminates it.
+// If publishing fails, msg is acknowledged with retry instead, to be
+// dead-lettered on redelivery.
+func (c *deadLetterer) deadLetter(msg jetstream.Msg, reason string, retry func(jetstream.Msg) error) error {
+	if err := c.publish(msg, reason); err != nil {
+		c.logger.Error("failed to publish dead letter",
+			zap.String("subject", msg.Subject()),
+			zap.String("dead_letter_subject", c.config.Subject),
+			zap.Error(err),
+		)
+		return retry(msg)
+	}
+	c.telemetry.ReceiverNatsDeadLetteredMessages.Add(context.Background(), 1)
+	c.logger.Warn("message dead-lettered",
+		zap.String("subject", msg.Subject()),
+		zap.String("dead_letter_subject", c.config.Subject),
+		zap.Uint64("deliveries", deliveries(msg)),
+		zap.String("reason", reason),
+	)
+	return msg.Term()
+}
+
+// publish sends a copy of the message carrying the dead letter headers.
+func (c *deadLetterer) publish(m jetstream.Msg, reason string) error {
+	out := nats.NewMsg(c.config.Subject)
+	for name, values := range m.Headers() {
+		// Publish expectations of the original message do not hold for the copy
+		if strings.HasPrefix(name, "Nats-Expected-") {
+			continue
+		}
+		out.Header[name] = slices.Clone(values)
+	}
+	out.Header.Set(internalnats.HeaderDeadLetterError, reason)
+	out.Header.Set(internalnats.HeaderDeadLetterSubject, m.Subject())
+	out.Header.Set(internalnats.HeaderDeadLetterDeliveries, strconv.FormatUint(deliveries(m), 10))
+	out.Data = m.Data()
+
+	if c.config.Stream == "" {
+		return c.js.Conn().PublishMsg(out)
+	}
+	if _, err := c.js.PublishMsg(context.Background(), out, jetstream.WithExpectStream(c.config.Stream)); err != nil {
+		return fmt.Errorf("stream %q: %w", c.config.Stream, err)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package natsreceiver
 
 import (
 	"context"
+	"time"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
@@ -17,6 +18,25 @@ const (
 	defaultLogsSubject    = "otel.logs"
 	defaultQueueGroup     = "otel-collector"
 	defaultEncoding       = "otlp_proto"
+
+	defaultMaxDecompressedSize = 64 << 20 // 64 MiB
+	defaultPartitionTTL        = 15 * time.Second
+
+	// defaultAckWait is the server's ack_wait for consumers that do not set one
+	defaultAckWait = 30 * time.Second
+
+	// Fetching from a bound consumer, as the SDK receiver does
+	defaultFetchBatchSize = 100
+	defaultFetchTimeout   = 5 * time.Second
+	minFetchTimeout       = time.Second
+	fetchRetryDelay       = 100 * time.Millisecond
+
+	defaultCoalesceMaxMessages = 100
+	defaultCoalesceTimeout     = 200 * time.Millisecond
+
+	// snapshotInactiveThreshold is how long the server keeps a snapshot
+	// consumer after its receiver stopped fetching
+	snapshotInactiveThreshold = time.Minute
 )
 
 // NewFactory creates a factory for the NATS receiver.
@@ -32,8 +52,9 @@ func NewFactory() receiver.Factory {
 
 func createDefaultConfig() component.Config {
 	return &Config{
-		ClientConfig: internalnats.NewDefaultClientConfig(),
-		QueueGroup:   defaultQueueGroup,
+		ClientConfig:        internalnats.NewDefaultClientConfig(),
+		QueueGroup:          defaultQueueGroup,
+		MaxDecompressedSize: defaultMaxDecompressedSize,
 		Traces: SignalConfig{
 			Subject:  defaultTracesSubject,
 			Encoding: defaultEncoding,
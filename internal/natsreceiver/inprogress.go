@@ -0,0 +1,89 @@
+package natsreceiver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mikluko/otelnats"
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+)
+
+// errInProgressExpired cancels a pipeline call that outlived in_progress.max_duration.
+var errInProgressExpired = errors.New("in_progress.max_duration exceeded")
+
+// keepInProgress sends in-progress acknowledgements for a JetStream message
+// while the pipeline consumes it with the returned context, so that it is
+// not redelivered after ack_wait. Once max_duration is exceeded, it gives up:
+// the message is negatively acknowledged and the context is canceled.
+// The returned function stops it when the pipeline returns.
+func (r *natsReceiver) keepInProgress(ctx context.Context, jsConfig *JetStreamConfig, msg otelnats.MessageCore) (context.Context, func()) {
+	m, ok := jetStreamMsgOf(msg)
+	if !ok {
+		return ctx, func() {}
+	}
+	return r.keepAllInProgress(ctx, jsConfig, []*jetStreamMsg{m})
+}
+
+// keepAllInProgress is keepInProgress for the messages behind a single
+// pipeline call.
+func (r *natsReceiver) keepAllInProgress(ctx context.Context, jsConfig *JetStreamConfig, msgs []*jetStreamMsg) (context.Context, func()) {
+	if jsConfig == nil || jsConfig.InProgress == nil || len(msgs) == 0 {
+		return ctx, func() {}
+	}
+
+	interval := jsConfig.InProgress.Interval
+	if interval == 0 {
+		interval = msgs[0].ackWait / 3
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var expired <-chan time.Time
+		if d := jsConfig.InProgress.MaxDuration; d > 0 {
+			t := time.NewTimer(d)
+			defer t.Stop()
+			expired = t.C
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, m := range msgs {
+					if err := m.InProgress(); err != nil {
+						r.logger.Debug("failed to send in-progress acknowledgement",
+							zap.String("subject", m.Subject()),
+							zap.Error(err),
+						)
+					}
+				}
+			case <-expired:
+				r.logger.Warn("giving up on messages still being consumed",
+					zap.Int("messages", len(msgs)),
+					zap.Duration("max_duration", jsConfig.InProgress.MaxDuration),
+				)
+				for _, m := range msgs {
+					delay := jsConfig.backoff(deliveries(m.Msg))
+					if err := m.settle(func(msg jetstream.Msg) error { return msg.NakWithDelay(delay) }); err != nil {
+						r.logger.Warn("failed to settle message, leaving it to the receiver",
+							zap.String("subject", m.Subject()),
+							zap.Error(err),
+						)
+					}
+				}
+				cancel(errInProgressExpired)
+				return
+			}
+		}
+	}()
+	return ctx, func() {
+		cancel(nil)
+		<-done
+	}
+}
@@ -0,0 +1,286 @@
+package natsreceiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/mikluko/otelnats"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracespb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"google.golang.org/protobuf/proto"
+)
+
+// jetStreamMessage adapts a fetched JetStream message to the SDK message
+// interface, so that it goes through the same handlers as any other message.
+// Handlers get the JetStream message back through jetStreamMsgOf.
+type jetStreamMessage[T any] struct {
+	*jetStreamMsg
+}
+
+func (m *jetStreamMessage[T]) Signal() (*T, error) {
+	return unmarshalSignal[T](m.Data(), m.Headers())
+}
+
+// unmarshalSignal decodes the payload of a message into the OTLP type T.
+func unmarshalSignal[T any](data []byte, headers nats.Header) (*T, error) {
+	var item T
+	if err := otelnats.Unmarshal(data, headers.Get(otelnats.HeaderContentType), any(&item).(proto.Message)); err != nil {
+		return nil, fmt.Errorf("%w: %v", otelnats.ErrUnmarshal, err)
+	}
+	return &item, nil
+}
+
+// jetStreamMsg is a message fetched by a jetStreamReceiver. A handler may
+// take over its acknowledgement, in which case the receiver sends none.
+type jetStreamMsg struct {
+	jetstream.Msg
+	receiver *jetStreamReceiver
+	ackWait  time.Duration // of the consumer that delivered the message
+	claimed  atomic.Bool   // the receiver's acknowledgement is dropped
+	settled  atomic.Bool   // settle was called
+}
+
+func (m *jetStreamMsg) jetStream() *jetStreamMsg {
+	return m
+}
+
+// jetStreamMsgOf returns the JetStream message behind a message handed to a
+// handler. ok is false for core NATS messages and requests.
+func jetStreamMsgOf(msg otelnats.MessageCore) (m *jetStreamMsg, ok bool) {
+	if js, ok := msg.(interface{ jetStream() *jetStreamMsg }); ok {
+		return js.jetStream(), true
+	}
+	return nil, false
+}
+
+// claim takes over the acknowledgement of the message from the receiver, to
+// be sent later through settle.
+func (m *jetStreamMsg) claim() {
+	m.claimed.Store(true)
+}
+
+// settle acknowledges the message on behalf of a handler by calling ack on
+// the underlying message, or leaves it unacknowledged to time out if ack is
+// nil. Only the first call has an effect. The receiver's acknowledgement is
+// dropped unless ack fails.
+func (m *jetStreamMsg) settle(ack func(jetstream.Msg) error) error {
+	if !m.settled.CompareAndSwap(false, true) {
+		return nil
+	}
+	if ack != nil {
+		if err := ack(m.Msg); err != nil {
+			return err
+		}
+	}
+	m.claim()
+	return nil
+}
+
+// deliveries returns the number of times msg was delivered, or 0 if its
+// metadata is not available.
+func deliveries(msg jetstream.Msg) uint64 {
+	md, err := msg.Metadata()
+	if err != nil {
+		return 0
+	}
+	return md.NumDelivered
+}
+
+// jetStreamReceiver consumes a durable or snapshot consumer bound by the
+// receiver, instead of the SDK receiver. Like the SDK, it waits for the rate
+// limiter before each fetch, hands each message to the signal's handler and
+// acknowledges it by the handler's result. Unlike the SDK, it does so in a
+// single goroutine that also records the fetch and acknowledgement telemetry,
+// and lets handlers settle the JetStream message themselves.
+type jetStreamReceiver struct {
+	r        *natsReceiver
+	consumer jetstream.Consumer
+	cfg      *SignalConfig
+
+	limiter      *rate.Limiter // nil without rate_limit
+	batch        int
+	fetchTimeout time.Duration
+	ackWait      time.Duration
+
+	deadLetter *deadLetterer // nil without dead_letter
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (r *natsReceiver) newJetStreamReceiver(consumer jetstream.Consumer, js jetstream.JetStream, cfg *SignalConfig) *jetStreamReceiver {
+	jsConfig := cfg.JetStream
+	j := &jetStreamReceiver{
+		r:        r,
+		consumer: consumer,
+		cfg:      cfg,
+		batch:    defaultFetchBatchSize,
+		ackWait:  defaultAckWait,
+	}
+	if info := consumer.CachedInfo(); info != nil && info.Config.AckWait > 0 {
+		j.ackWait = info.Config.AckWait
+	}
+	if jsConfig.RateLimit > 0 {
+		j.limiter = rate.NewLimiter(rate.Limit(jsConfig.RateLimit), jsConfig.RateBurst)
+		j.batch = jsConfig.RateBurst
+	}
+	j.fetchTimeout = j.calculateFetchTimeout(jsConfig.RateLimit)
+	if cfg.DeadLetter != nil {
+		j.deadLetter = newDeadLetterer(js, cfg.DeadLetter, r.telemetry, r.logger)
+	}
+	return j
+}
+
+// calculateFetchTimeout returns the expiry of each fetch: long enough to
+// consume a batch at rateLimit if set, but never above ack_wait, so that
+// fetched messages do not time out before they are handled.
+func (j *jetStreamReceiver) calculateFetchTimeout(rateLimit float64) time.Duration {
+	timeout := defaultFetchTimeout
+	if rateLimit > 0 {
+		timeout = time.Duration(float64(j.batch)/rateLimit*float64(time.Second)) + minFetchTimeout
+	}
+	return max(min(timeout, j.ackWait), minFetchTimeout)
+}
+
+// Start starts fetching in the background.
+func (j *jetStreamReceiver) Start(context.Context) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	j.cancel = cancel
+	j.done = make(chan struct{})
+	go j.run(ctx)
+	return nil
+}
+
+// Shutdown stops fetching and waits for the message being handled. Messages
+// fetched but not handled yet are negatively acknowledged.
+func (j *jetStreamReceiver) Shutdown(ctx context.Context) error {
+	if j.cancel == nil {
+		return nil
+	}
+	j.cancel()
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (j *jetStreamReceiver) run(ctx context.Context) {
+	defer close(j.done)
+	telemetry := j.r.telemetry
+	for {
+		if j.limiter != nil {
+			start := time.Now()
+			if err := j.limiter.WaitN(ctx, j.batch); err != nil {
+				return
+			}
+			telemetry.ReceiverNatsRateLimitWait.Record(ctx, time.Since(start).Seconds())
+		}
+
+		batch, err := j.consumer.Fetch(j.batch, jetstream.FetchMaxWait(j.fetchTimeout))
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// ErrNoMessages is normal when no messages are available
+			if !errors.Is(err, jetstream.ErrNoMessages) {
+				j.r.handleError(err)
+				select {
+				case <-time.After(fetchRetryDelay):
+				case <-ctx.Done():
+					return
+				}
+			}
+			continue
+		}
+
+		n := 0
+		msgs := batch.Messages()
+	handle:
+		for {
+			select {
+			case <-ctx.Done():
+				// Return the rest of the batch for redelivery
+				for msg := range msgs {
+					m := &meteredMsg{Msg: msg, telemetry: telemetry}
+					if err := m.Nak(); err != nil {
+						j.r.handleError(err)
+					}
+				}
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					break handle
+				}
+				n++
+				j.handle(msg)
+			}
+		}
+		telemetry.ReceiverNatsFetchFillRatio.Record(ctx, float64(n)/float64(j.batch))
+	}
+}
+
+// handle hands a fetched message to the handler of its signal and
+// acknowledges it by the result, applying the redelivery and dead letter
+// settings if it failed.
+func (j *jetStreamReceiver) handle(msg jetstream.Msg) {
+	ctx := context.Background()
+	if deliveries(msg) > 1 {
+		j.r.telemetry.ReceiverNatsRedeliveredMessages.Add(ctx, 1)
+	}
+
+	m := &jetStreamMsg{
+		Msg:      &meteredMsg{Msg: msg, telemetry: j.r.telemetry},
+		receiver: j,
+		ackWait:  j.ackWait,
+	}
+
+	signal := j.r.signal()
+	var err error
+	switch got := m.Headers().Get(otelnats.HeaderOtelSignal); {
+	case got != signal:
+		err = fmt.Errorf("%w: %q", otelnats.ErrUnknownSignal, got)
+		if termErr := m.Term(); termErr != nil {
+			err = fmt.Errorf("%w (term failed: %v)", err, termErr)
+		}
+		j.r.handleError(err)
+		return
+	case signal == otelnats.SignalTraces:
+		err = j.r.handleTracesMessage(ctx, &jetStreamMessage[tracespb.TracesData]{m})
+	case signal == otelnats.SignalMetrics:
+		err = j.r.handleMetricsMessage(ctx, &jetStreamMessage[metricspb.MetricsData]{m})
+	default:
+		err = j.r.handleLogsMessage(ctx, &jetStreamMessage[logspb.LogsData]{m})
+	}
+	if err != nil {
+		j.r.handleError(err)
+	}
+	if m.claimed.Load() {
+		// Settled by the handler, or to be settled by a coalescer
+		return
+	}
+
+	ack := jetstream.Msg.Ack
+	if err != nil {
+		ack = j.failureAck(m, err)
+	}
+	if ack == nil {
+		// Left to time out
+		return
+	}
+	if err := ack(m.Msg); err != nil && !errors.Is(err, jetstream.ErrMsgAlreadyAckd) {
+		j.r.logger.Warn("failed to acknowledge message",
+			zap.String("subject", m.Subject()),
+			zap.Error(err),
+		)
+	}
+}
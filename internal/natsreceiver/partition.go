@@ -0,0 +1,312 @@
+package natsreceiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nats-io/nuid"
+	"go.uber.org/zap"
+
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+)
+
+// partitionManager registers the receiver as a member in a KV bucket and
+// keeps the partitions assigned to it consumed as members join and expire.
+// Members renew their key every ttl/3; the bucket's TTL removes the keys of
+// members that stopped.
+type partitionManager struct {
+	kv       jetstream.KeyValue
+	prefix   string // key prefix shared by the members, "<signal>."
+	key      string // own member key
+	count    int
+	interval time.Duration
+	logger   *zap.Logger
+
+	// open starts consuming a partition and returns the function stopping it
+	open func(ctx context.Context, p int) (func(context.Context) error, error)
+
+	mu     sync.Mutex
+	owned  map[int]func(context.Context) error
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startPartitions joins the members of the signal's partitions and starts
+// consuming the partitions assigned to this receiver.
+func (r *natsReceiver) startPartitions(ctx context.Context, js jetstream.JetStream, cfg *SignalConfig) error {
+	pc := cfg.Partitions
+	ttl := pc.TTL
+	if ttl == 0 {
+		ttl = defaultPartitionTTL
+	}
+
+	kv, err := js.KeyValue(ctx, pc.Bucket)
+	if errors.Is(err, jetstream.ErrBucketNotFound) {
+		kv, err = js.CreateKeyValue(ctx, jetstream.KeyValueConfig{Bucket: pc.Bucket, TTL: ttl})
+		if errors.Is(err, jetstream.ErrBucketExists) {
+			// Another member created it first
+			kv, err = js.KeyValue(ctx, pc.Bucket)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to bind partition bucket %q: %w", pc.Bucket, err)
+	}
+
+	signal := r.signal()
+	m := &partitionManager{
+		kv:       kv,
+		prefix:   signal + ".",
+		key:      signal + "." + nuid.Next(),
+		count:    pc.Count,
+		interval: ttl / 3,
+		logger:   r.logger.With(zap.String("bucket", pc.Bucket)),
+		owned:    map[int]func(context.Context) error{},
+	}
+	if cfg.JetStream != nil {
+		m.open = func(ctx context.Context, p int) (func(context.Context) error, error) {
+			return r.consumePartition(ctx, js, cfg, p)
+		}
+	} else {
+		m.open = func(_ context.Context, p int) (func(context.Context) error, error) {
+			return r.subscribePartition(partitionSubject(cfg.Subject, p), pc.Bucket)
+		}
+	}
+	r.partitions = m
+	if err := m.start(ctx, r.settings.ID.String()); err != nil {
+		return err
+	}
+
+	r.logger.Info("NATS receiver started (partitioned mode)",
+		internalnats.ConnectionField(r.config.Connection, r.config.ClientConfig),
+		zap.String("subject", cfg.Subject),
+		zap.Int("partitions", pc.Count),
+		zap.String("member", m.key),
+	)
+	return nil
+}
+
+// subscribePartition subscribes to a core NATS partition subject. Messages
+// are handled like requests, which also answers exporters using request_reply.
+// All members subscribe in queueGroup, so that while a partition changes
+// hands, each message still goes to only one of its old and new owner.
+func (r *natsReceiver) subscribePartition(subject, queueGroup string) (func(context.Context) error, error) {
+	sub, err := r.conn.QueueSubscribe(subject, queueGroup, r.handleRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to %q: %w", subject, err)
+	}
+	if err := r.conn.Flush(); err != nil {
+		_ = sub.Unsubscribe()
+		return nil, err
+	}
+	return func(context.Context) error {
+		if err := sub.Unsubscribe(); err != nil && !errors.Is(err, nats.ErrConnectionClosed) {
+			return err
+		}
+		return nil
+	}, nil
+}
+
+// consumePartition consumes a partition through its own durable consumer,
+// <consumer>-<partition>, so that a new owner resumes where the previous
+// one stopped.
+func (r *natsReceiver) consumePartition(ctx context.Context, js jetstream.JetStream, cfg *SignalConfig, p int) (func(context.Context) error, error) {
+	jsConfig := cfg.JetStream
+	var policy string
+	if jsConfig.Provision != nil {
+		policy = jsConfig.Provision.Policy
+	}
+	durable := jsConfig.Consumer + "-" + strconv.Itoa(p)
+	consumer, err := internalnats.ProvisionConsumer(ctx, js, jsConfig.Stream, policy,
+		jsConfig.consumerConfig(durable, []string{partitionSubject(cfg.Subject, p)}), r.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	receiver := r.newJetStreamReceiver(consumer, js, cfg)
+	if err := receiver.Start(ctx); err != nil {
+		return nil, err
+	}
+	return receiver.Shutdown, nil
+}
+
+// partitionSubject returns the subject of partition p, as published by an
+// exporter with partitions.
+func partitionSubject(subject string, p int) string {
+	return subject + "." + strconv.Itoa(p)
+}
+
+// start registers the member, takes over its partitions and keeps
+// heartbeating and rebalancing in the background.
+func (m *partitionManager) start(ctx context.Context, value string) error {
+	if _, err := m.kv.PutString(ctx, m.key, value); err != nil {
+		return fmt.Errorf("failed to register partition member: %w", err)
+	}
+	if err := m.rebalance(ctx); err != nil {
+		return err
+	}
+
+	loopCtx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.done = make(chan struct{})
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-loopCtx.Done():
+				return
+			case <-ticker.C:
+			}
+			if _, err := m.kv.PutString(loopCtx, m.key, value); err != nil {
+				m.logger.Warn("failed to renew partition membership", zap.Error(err))
+				continue
+			}
+			if err := m.rebalance(loopCtx); err != nil && loopCtx.Err() == nil {
+				m.logger.Warn("failed to rebalance partitions", zap.Error(err))
+			}
+		}
+	}()
+	return nil
+}
+
+// rebalance computes the partitions assigned to this member from the live
+// members and starts or stops consuming partitions accordingly.
+func (m *partitionManager) rebalance(ctx context.Context) error {
+	members, err := m.members(ctx)
+	if err != nil {
+		return err
+	}
+	assigned := assignPartitions(members, m.key, m.count)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs error
+	changed := false
+	for p, stop := range m.owned {
+		if !slices.Contains(assigned, p) {
+			errs = errors.Join(errs, stop(ctx))
+			delete(m.owned, p)
+			changed = true
+		}
+	}
+	for _, p := range assigned {
+		if _, ok := m.owned[p]; ok {
+			continue
+		}
+		stop, err := m.open(ctx, p)
+		if err != nil {
+			// Retried on the next rebalance
+			errs = errors.Join(errs, fmt.Errorf("partition %d: %w", p, err))
+			continue
+		}
+		m.owned[p] = stop
+		changed = true
+	}
+	if changed {
+		m.logger.Info("Partitions rebalanced",
+			zap.Int("members", len(members)),
+			zap.Ints("partitions", assigned),
+		)
+	}
+	return errs
+}
+
+// members lists the keys of the live members, including this one.
+func (m *partitionManager) members(ctx context.Context) ([]string, error) {
+	lister, err := m.kv.ListKeysFiltered(ctx, m.prefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partition members: %w", err)
+	}
+	members := []string{m.key}
+	for key := range lister.Keys() {
+		if key != m.key {
+			members = append(members, key)
+		}
+	}
+	return members, nil
+}
+
+// shutdown stops heartbeating, stops consuming all partitions and
+// deregisters the member so that the others take over right away.
+func (m *partitionManager) shutdown(ctx context.Context) error {
+	if m.cancel != nil {
+		m.cancel()
+		<-m.done
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Stop partitions concurrently; each may wait for an in-flight fetch
+	var wg sync.WaitGroup
+	errCh := make(chan error, len(m.owned))
+	for p, stop := range m.owned {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errCh <- stop(ctx)
+		}()
+		delete(m.owned, p)
+	}
+	wg.Wait()
+	close(errCh)
+
+	var errs error
+	for err := range errCh {
+		errs = errors.Join(errs, err)
+	}
+	if err := m.kv.Delete(ctx, m.key); err != nil && !errors.Is(err, nats.ErrConnectionClosed) {
+		errs = errors.Join(errs, err)
+	}
+	return errs
+}
+
+// assignPartitions returns the partitions owned by member among members,
+// using rendezvous hashing: each partition goes to the member with the
+// highest score, so a joining or leaving member only moves the partitions
+// it gains or loses.
+func assignPartitions(members []string, member string, count int) []int {
+	var owned []int
+	for p := 0; p < count; p++ {
+		var best string
+		var bestScore uint64
+		for _, m := range members {
+			score := partitionScore(m, p)
+			if best == "" || score > bestScore || (score == bestScore && strings.Compare(m, best) < 0) {
+				best, bestScore = m, score
+			}
+		}
+		if best == member {
+			owned = append(owned, p)
+		}
+	}
+	return owned
+}
+
+// partitionScore is the rendezvous hashing score of member for partition p.
+func partitionScore(member string, p int) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(member))
+	_, _ = h.Write([]byte{'.'})
+	_, _ = h.Write([]byte(strconv.Itoa(p)))
+	// FNV barely mixes inputs differing in the last bytes; finish with the
+	// MurmurHash3 finalizer so that scores are uniform across partitions
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
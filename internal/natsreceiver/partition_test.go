@@ -0,0 +1,46 @@
+package natsreceiver
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssignPartitions(t *testing.T) {
+	members := []string{"traces.a", "traces.b", "traces.c"}
+
+	owners := map[int]string{}
+	for _, m := range members {
+		for _, p := range assignPartitions(members, m, 64) {
+			_, dup := owners[p]
+			assert.False(t, dup, "partition %d assigned twice", p)
+			owners[p] = m
+		}
+	}
+	assert.Len(t, owners, 64)
+	for _, m := range members {
+		assert.NotEmpty(t, assignPartitions(members, m, 64), "member %s owns no partition", m)
+	}
+
+	// Removing a member only moves its own partitions
+	remaining := members[:2]
+	for _, m := range remaining {
+		for _, p := range assignPartitions(remaining, m, 64) {
+			if owners[p] != "traces.c" {
+				assert.Equal(t, owners[p], m, fmt.Sprintf("partition %d moved", p))
+			}
+		}
+	}
+
+	// Two members split four partitions without overlap
+	pair := []string{"logs.a", "logs.c"}
+	first, second := assignPartitions(pair, "logs.a", 4), assignPartitions(pair, "logs.c", 4)
+	assert.NotEmpty(t, first)
+	assert.NotEmpty(t, second)
+	assert.ElementsMatch(t, []int{0, 1, 2, 3}, append(slices.Clone(first), second...))
+
+	// A single member owns everything
+	assert.Len(t, assignPartitions([]string{"traces.a"}, "traces.a", 8), 8)
+}
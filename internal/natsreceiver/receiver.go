@@ -8,6 +8,7 @@ import (
 	"github.com/mikluko/otelnats"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/collector/client"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/consumer/consumererror"
@@ -22,6 +23,7 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
+	"github.com/mikluko/otelnats-collector/internal/metadata"
 	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
 )
 
@@ -31,10 +33,30 @@ type natsReceiver struct {
 	logger   *zap.Logger
 	obsrecv  *receiverhelper.ObsReport
 
+	// telemetry records the NATS specific metrics not covered by obsrecv
+	telemetry *metadata.TelemetryBuilder
+
 	downstreamErrLevel zapcore.Level
 
-	conn        *nats.Conn
-	sdkReceiver otelnats.Receiver
+	conn       *nats.Conn
+	ownsConn   bool               // false when conn is shared through the nats extension
+	receiver   otelnats.Receiver  // SDK receiver, or jetStreamReceiver for a consumer bound by the receiver
+	requestSub *nats.Subscription // request_reply mode bypasses the SDK receiver
+	partitions *partitionManager  // set when partitions are configured
+
+	// Coalescers merging the data of consecutive messages, if configured
+	tracesCoalescer  *coalescer[ptrace.Traces]
+	metricsCoalescer *coalescer[pmetric.Metrics]
+	logsCoalescer    *coalescer[plog.Logs]
+
+	// stopDeferred stops a start deferred until NATS is reachable
+	stopDeferred func()
+
+	// Decompressor for payloads with a Content-Encoding header
+	decompressor *internalnats.Decompressor
+
+	// Resolver for payloads stored in Object Store by claim-check
+	claimCheck *claimCheckResolver
 
 	// Standard pdata unmarshalers (Kafka pattern)
 	tracesUnmarshaler      ptrace.Unmarshaler
@@ -65,11 +87,27 @@ func newNatsReceiver(
 		return nil, err
 	}
 
-	return &natsReceiver{
+	telemetry, err := metadata.NewTelemetryBuilder(set.TelemetrySettings)
+	if err != nil {
+		return nil, err
+	}
+
+	maxDecompressedSize := cfg.MaxDecompressedSize
+	if maxDecompressedSize == 0 {
+		maxDecompressedSize = defaultMaxDecompressedSize
+	}
+	decompressor, err := internalnats.NewDecompressor(maxDecompressedSize)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &natsReceiver{
 		config:                 cfg,
 		settings:               set,
 		logger:                 set.Logger,
 		obsrecv:                obsrecv,
+		telemetry:              telemetry,
+		decompressor:           decompressor,
 		tracesConsumer:         tracesConsumer,
 		metricsConsumer:        metricsConsumer,
 		logsConsumer:           logsConsumer,
@@ -79,20 +117,49 @@ func newNatsReceiver(
 		tracesJSONUnmarshaler:  &ptrace.JSONUnmarshaler{},
 		metricsJSONUnmarshaler: &pmetric.JSONUnmarshaler{},
 		logsJSONUnmarshaler:    &plog.JSONUnmarshaler{},
-	}, nil
+	}
+	if tracesConsumer != nil && cfg.Traces.Coalesce != nil {
+		r.tracesCoalescer = r.newTracesCoalescer(&cfg.Traces)
+	}
+	if metricsConsumer != nil && cfg.Metrics.Coalesce != nil {
+		r.metricsCoalescer = r.newMetricsCoalescer(&cfg.Metrics)
+	}
+	if logsConsumer != nil && cfg.Logs.Coalesce != nil {
+		r.logsCoalescer = r.newLogsCoalescer(&cfg.Logs)
+	}
+	return r, nil
 }
 
-func (r *natsReceiver) Start(ctx context.Context, _ component.Host) error {
+func (r *natsReceiver) Start(ctx context.Context, host component.Host) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	// Connect to NATS
-	conn, err := internalnats.Connect(ctx, r.config.ClientConfig, r.logger)
+	// Connect to NATS, or use the connection shared by the nats extension
+	conn, owned, err := internalnats.GetConnection(ctx, host, r.config.Connection, r.config.ClientConfig, r.logger)
 	if err != nil {
 		return err
 	}
 	r.conn = conn
+	r.ownsConn = owned
+
+	if err := internalnats.RegisterConnectionTelemetry(r.telemetry, conn); err != nil {
+		return err
+	}
+
+	// Subscribe right away, or in the background once the connection is
+	// established if NATS is not reachable yet
+	r.stopDeferred, err = internalnats.StartWhenConnected(ctx, host, conn, r.logger, r.subscribe)
+	return err
+}
+
+// subscribe sets up the subscription or JetStream consumer of the receiver.
+func (r *natsReceiver) subscribe(ctx context.Context) error {
+	js, err := jetstream.New(r.conn)
+	if err != nil {
+		return fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+	r.claimCheck = newClaimCheckResolver(js, r.config.ClaimCheck.Buckets, r.decompressor.MaxSize())
 
 	// Build SDK receiver options (same for both core NATS and JetStream)
 	opts := []otelnats.ReceiverOption{
@@ -103,6 +170,7 @@ func (r *natsReceiver) Start(ctx context.Context, _ component.Host) error {
 	// Determine which signal is enabled and get its configuration
 	var signalConfig *SignalConfig
 	var jsConfig *JetStreamConfig
+	var consumer jetstream.Consumer // bound consumer, fetched by a jetStreamReceiver
 
 	// Register handlers and subjects only for enabled signals (where consumer is not nil)
 	if r.tracesConsumer != nil {
@@ -142,16 +210,8 @@ func (r *natsReceiver) Start(ctx context.Context, _ component.Host) error {
 
 		r.downstreamErrLevel = zap.WarnLevel
 
-		js, err := jetstream.New(conn)
-		if err != nil {
-			return fmt.Errorf("failed to create JetStream context: %w", err)
-		}
-
 		opts = append(opts, otelnats.WithReceiverJetStream(js, jsConfig.Stream))
 
-		if jsConfig.Consumer != "" {
-			opts = append(opts, otelnats.WithReceiverConsumerName(jsConfig.Consumer))
-		}
 		if jsConfig.AckWait > 0 {
 			opts = append(opts, otelnats.WithReceiverAckWait(jsConfig.AckWait))
 		}
@@ -161,6 +221,30 @@ func (r *natsReceiver) Start(ctx context.Context, _ component.Host) error {
 		if jsConfig.RateLimit > 0 {
 			opts = append(opts, otelnats.WithReceiverRateLimit(jsConfig.RateLimit, jsConfig.RateBurst))
 		}
+
+		if signalConfig.Partitions != nil {
+			return r.startPartitions(ctx, js, signalConfig)
+		}
+		if signalConfig.Snapshot {
+			if consumer, err = r.snapshotConsumer(ctx, js, signalConfig.Subject, jsConfig); err != nil {
+				return err
+			}
+		} else if jsConfig.Provision != nil {
+			if consumer, err = r.provisionConsumer(ctx, js, signalConfig.Subject, jsConfig); err != nil {
+				return err
+			}
+		} else if jsConfig.Consumer != "" {
+			// Bind the consumer, creating it if missing
+			consumer, err = js.Consumer(ctx, jsConfig.Stream, jsConfig.Consumer)
+			if errors.Is(err, jetstream.ErrConsumerNotFound) {
+				consumer, err = r.provisionConsumer(ctx, js, signalConfig.Subject, jsConfig)
+				if err != nil {
+					return err
+				}
+			} else if err != nil {
+				return fmt.Errorf("failed to bind consumer %q on stream %q: %w", jsConfig.Consumer, jsConfig.Stream, err)
+			}
+		}
 	} else {
 		// Core NATS mode - use signal-specific queue group if available, otherwise connection-level
 
@@ -173,23 +257,45 @@ func (r *natsReceiver) Start(ctx context.Context, _ component.Host) error {
 		if queueGroup != "" {
 			opts = append(opts, otelnats.WithReceiverQueueGroup(queueGroup))
 		}
-	}
 
-	// Create and start SDK receiver
-	sdkReceiver, err := otelnats.NewReceiver(conn, opts...)
-	if err != nil {
-		return fmt.Errorf("failed to create SDK receiver: %w", err)
+		if signalConfig != nil && signalConfig.Partitions != nil {
+			return r.startPartitions(ctx, js, signalConfig)
+		}
+		if signalConfig != nil && signalConfig.RequestReply {
+			if err := r.subscribeRequests(signalConfig.Subject, queueGroup); err != nil {
+				return err
+			}
+			r.logger.Info("NATS receiver started (request-reply mode)",
+				internalnats.ConnectionField(r.config.Connection, r.config.ClientConfig),
+				zap.String("subject", signalConfig.Subject),
+				zap.String("queue_group", queueGroup),
+			)
+			return nil
+		}
 	}
-	r.sdkReceiver = sdkReceiver
 
-	if err := r.sdkReceiver.Start(ctx); err != nil {
-		return fmt.Errorf("failed to start SDK receiver: %w", err)
+	// Fetch from a bound consumer directly, anything else through the SDK receiver
+	if consumer != nil {
+		r.receiver = r.newJetStreamReceiver(consumer, js, signalConfig)
+		if err := r.receiver.Start(ctx); err != nil {
+			return err
+		}
+	} else {
+		sdkReceiver, err := otelnats.NewReceiver(r.conn, opts...)
+		if err != nil {
+			return fmt.Errorf("failed to create SDK receiver: %w", err)
+		}
+		r.receiver = sdkReceiver
+
+		if err := r.receiver.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start SDK receiver: %w", err)
+		}
 	}
 
 	// Log startup info
 	if jsConfig != nil {
 		fields := []zap.Field{
-			zap.String("url", r.config.URL),
+			internalnats.ConnectionField(r.config.Connection, r.config.ClientConfig),
 			zap.String("stream", jsConfig.Stream),
 			zap.String("consumer", jsConfig.Consumer),
 		}
@@ -206,7 +312,7 @@ func (r *natsReceiver) Start(ctx context.Context, _ component.Host) error {
 			queueGroup = signalConfig.QueueGroup
 		}
 		r.logger.Info("NATS receiver started (core NATS mode)",
-			zap.String("url", r.config.URL),
+			internalnats.ConnectionField(r.config.Connection, r.config.ClientConfig),
 			zap.String("queue_group", queueGroup),
 		)
 	}
@@ -214,135 +320,337 @@ func (r *natsReceiver) Start(ctx context.Context, _ component.Host) error {
 	return nil
 }
 
+// provisionConsumer creates or verifies the durable consumer as configured
+// and returns it. Filter subjects default to the signal subject. Without
+// provision settings, a missing consumer is created with the defaults.
+func (r *natsReceiver) provisionConsumer(ctx context.Context, js jetstream.JetStream, subject string, jsConfig *JetStreamConfig) (jetstream.Consumer, error) {
+	p := jsConfig.Provision
+	if p == nil {
+		p = &ConsumerProvisionConfig{}
+	}
+	filterSubjects := p.FilterSubjects
+	if len(filterSubjects) == 0 {
+		filterSubjects = []string{subject}
+	}
+	return internalnats.ProvisionConsumer(ctx, js, jsConfig.Stream, p.Policy, jsConfig.consumerConfig(jsConfig.Consumer, filterSubjects), r.logger)
+}
+
+// deliverPolicy returns the configured deliver policy name, falling back to
+// the deprecated provision.deliver_policy.
+func (c *JetStreamConfig) deliverPolicy() string {
+	if c.DeliverPolicy == "" && c.Provision != nil {
+		return c.Provision.DeliverPolicy
+	}
+	return c.DeliverPolicy
+}
+
+// consumerConfig returns the configuration of a durable consumer the
+// receiver creates, starting where deliver_policy says.
+func (c *JetStreamConfig) consumerConfig(durable string, filterSubjects []string) jetstream.ConsumerConfig {
+	// Values were checked by Config.Validate
+	deliverPolicy, _ := internalnats.ParseDeliverPolicy(c.deliverPolicy())
+	replayPolicy, _ := internalnats.ParseReplayPolicy(c.ReplayPolicy)
+
+	cfg := jetstream.ConsumerConfig{
+		Durable:        durable,
+		AckPolicy:      jetstream.AckExplicitPolicy,
+		AckWait:        c.AckWait,
+		DeliverPolicy:  deliverPolicy,
+		ReplayPolicy:   replayPolicy,
+		FilterSubjects: filterSubjects,
+	}
+	switch deliverPolicy {
+	case jetstream.DeliverByStartSequencePolicy:
+		cfg.OptStartSeq = c.OptStartSeq
+	case jetstream.DeliverByStartTimePolicy:
+		startTime := c.OptStartTime
+		cfg.OptStartTime = &startTime
+	}
+	return cfg
+}
+
+// snapshotConsumer creates an ephemeral consumer delivering the latest
+// message of every series subject under subject, followed by live updates.
+func (r *natsReceiver) snapshotConsumer(ctx context.Context, js jetstream.JetStream, subject string, jsConfig *JetStreamConfig) (jetstream.Consumer, error) {
+	// Value was checked by Config.Validate
+	replayPolicy, _ := internalnats.ParseReplayPolicy(jsConfig.ReplayPolicy)
+	consumer, err := js.CreateConsumer(ctx, jsConfig.Stream, jetstream.ConsumerConfig{
+		AckPolicy:         jetstream.AckExplicitPolicy,
+		AckWait:           jsConfig.AckWait,
+		DeliverPolicy:     jetstream.DeliverLastPerSubjectPolicy,
+		ReplayPolicy:      replayPolicy,
+		FilterSubject:     subject + ".>",
+		InactiveThreshold: snapshotInactiveThreshold,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot consumer on stream %q: %w", jsConfig.Stream, err)
+	}
+	r.logger.Info("Reading metrics snapshot",
+		zap.String("stream", jsConfig.Stream),
+		zap.String("subject", subject+".>"),
+		zap.Uint64("series", consumer.CachedInfo().NumPending),
+	)
+	return consumer, nil
+}
+
 func (r *natsReceiver) Shutdown(ctx context.Context) error {
-	if r.sdkReceiver != nil {
-		if err := r.sdkReceiver.Shutdown(ctx); err != nil {
+	if r.stopDeferred != nil {
+		r.stopDeferred()
+	}
+	if r.partitions != nil {
+		if err := r.partitions.shutdown(ctx); err != nil {
+			return err
+		}
+	}
+	if r.requestSub != nil {
+		if err := r.requestSub.Unsubscribe(); err != nil && !errors.Is(err, nats.ErrConnectionClosed) {
 			return err
 		}
 	}
+	if r.receiver != nil {
+		if err := r.receiver.Shutdown(ctx); err != nil {
+			return err
+		}
+	}
+	// Receivers are stopped, flush what the coalescers still hold
+	if r.tracesCoalescer != nil {
+		r.tracesCoalescer.shutdown()
+	}
+	if r.metricsCoalescer != nil {
+		r.metricsCoalescer.shutdown()
+	}
+	if r.logsCoalescer != nil {
+		r.logsCoalescer.shutdown()
+	}
 
-	if r.conn != nil {
+	if r.conn != nil && r.ownsConn {
 		r.conn.Close()
 	}
+	r.telemetry.Shutdown()
+	r.decompressor.Close()
 	return nil
 }
 
 // Message handlers using SDK MessageSignal API (works for both core NATS and JetStream)
 
 func (r *natsReceiver) handleTracesMessage(ctx context.Context, msg otelnats.MessageSignal[tracespb.TracesData]) error {
-	ctx = r.obsrecv.StartTracesOp(ctx)
+	ctx = r.obsrecv.StartTracesOp(r.clientContext(ctx, msg))
 
 	// Choose unmarshaler based on Content-Type header
 	contentType := msg.Headers().Get(otelnats.HeaderContentType)
 	var traces ptrace.Traces
-	var err error
 
-	if contentType == otelnats.ContentTypeJSON {
-		traces, err = r.tracesJSONUnmarshaler.UnmarshalTraces(msg.Data())
-	} else {
-		// Default to protobuf (application/x-protobuf or empty)
-		traces, err = r.tracesUnmarshaler.UnmarshalTraces(msg.Data())
+	data, err := r.payload(ctx, msg)
+	if err == nil {
+		if contentType == otelnats.ContentTypeJSON {
+			traces, err = r.tracesJSONUnmarshaler.UnmarshalTraces(data)
+		} else {
+			// Default to protobuf (application/x-protobuf or empty)
+			traces, err = r.tracesUnmarshaler.UnmarshalTraces(data)
+		}
+		if err != nil {
+			err = decodeError{err}
+		}
 	}
 
 	if err != nil {
 		r.obsrecv.EndTracesOp(ctx, contentType, 0, err)
-		r.logger.Error("failed to unmarshal traces",
+		r.logger.Error("failed to decode traces",
 			zap.String("subject", msg.Subject()),
 			zap.String("content_type", contentType),
+			zap.String("content_encoding", msg.Headers().Get(internalnats.HeaderContentEncoding)),
 			zap.Error(err),
 		)
 		return err
 	}
 
+	if r.tracesCoalescer != nil {
+		// Counted by the coalescer once the merged data is consumed
+		r.obsrecv.EndTracesOp(ctx, contentType, 0, nil)
+		r.tracesCoalescer.add(r.coalesced(msg), contentType, traces)
+		return nil
+	}
+
 	spanCount := traces.SpanCount()
-	err = r.tracesConsumer.ConsumeTraces(ctx, traces)
+	consumeCtx, stop := r.keepInProgress(ctx, r.config.Traces.JetStream, msg)
+	err = r.tracesConsumer.ConsumeTraces(consumeCtx, traces)
+	stop()
 	r.obsrecv.EndTracesOp(ctx, contentType, spanCount, err)
 
 	if err != nil {
 		return receiverError{
 			err:    err,
+			items:  spanCount,
 			fields: []zap.Field{zap.String("subject", msg.Subject())},
 		}
 	}
+	r.releaseClaimCheck(ctx, msg)
 	return nil
 }
 
 func (r *natsReceiver) handleMetricsMessage(ctx context.Context, msg otelnats.MessageSignal[metricspb.MetricsData]) error {
-	ctx = r.obsrecv.StartMetricsOp(ctx)
+	ctx = r.obsrecv.StartMetricsOp(r.clientContext(ctx, msg))
 
 	// Choose unmarshaler based on Content-Type header
 	contentType := msg.Headers().Get(otelnats.HeaderContentType)
 	var metrics pmetric.Metrics
-	var err error
 
-	if contentType == otelnats.ContentTypeJSON {
-		metrics, err = r.metricsJSONUnmarshaler.UnmarshalMetrics(msg.Data())
-	} else {
-		// Default to protobuf (application/x-protobuf or empty)
-		metrics, err = r.metricsUnmarshaler.UnmarshalMetrics(msg.Data())
+	data, err := r.payload(ctx, msg)
+	if err == nil {
+		if contentType == otelnats.ContentTypeJSON {
+			metrics, err = r.metricsJSONUnmarshaler.UnmarshalMetrics(data)
+		} else {
+			// Default to protobuf (application/x-protobuf or empty)
+			metrics, err = r.metricsUnmarshaler.UnmarshalMetrics(data)
+		}
+		if err != nil {
+			err = decodeError{err}
+		}
 	}
 
 	if err != nil {
 		r.obsrecv.EndMetricsOp(ctx, contentType, 0, err)
-		r.logger.Error("failed to unmarshal metrics",
+		r.logger.Error("failed to decode metrics",
 			zap.String("subject", msg.Subject()),
 			zap.String("content_type", contentType),
+			zap.String("content_encoding", msg.Headers().Get(internalnats.HeaderContentEncoding)),
 			zap.Error(err),
 		)
 		return err
 	}
 
+	if r.metricsCoalescer != nil {
+		// Counted by the coalescer once the merged data is consumed
+		r.obsrecv.EndMetricsOp(ctx, contentType, 0, nil)
+		r.metricsCoalescer.add(r.coalesced(msg), contentType, metrics)
+		return nil
+	}
+
 	dataPointCount := metrics.DataPointCount()
-	err = r.metricsConsumer.ConsumeMetrics(ctx, metrics)
+	consumeCtx, stop := r.keepInProgress(ctx, r.config.Metrics.JetStream, msg)
+	err = r.metricsConsumer.ConsumeMetrics(consumeCtx, metrics)
+	stop()
 	r.obsrecv.EndMetricsOp(ctx, contentType, dataPointCount, err)
 
 	if err != nil {
 		return receiverError{
 			err:    err,
+			items:  dataPointCount,
 			fields: []zap.Field{zap.String("subject", msg.Subject())},
 		}
 	}
+	r.releaseClaimCheck(ctx, msg)
 	return nil
 }
 
 func (r *natsReceiver) handleLogsMessage(ctx context.Context, msg otelnats.MessageSignal[logspb.LogsData]) error {
-	ctx = r.obsrecv.StartLogsOp(ctx)
+	ctx = r.obsrecv.StartLogsOp(r.clientContext(ctx, msg))
 
 	// Choose unmarshaler based on Content-Type header
 	contentType := msg.Headers().Get(otelnats.HeaderContentType)
 	var logs plog.Logs
-	var err error
 
-	if contentType == otelnats.ContentTypeJSON {
-		logs, err = r.logsJSONUnmarshaler.UnmarshalLogs(msg.Data())
-	} else {
-		// Default to protobuf (application/x-protobuf or empty)
-		logs, err = r.logsUnmarshaler.UnmarshalLogs(msg.Data())
+	data, err := r.payload(ctx, msg)
+	if err == nil {
+		if contentType == otelnats.ContentTypeJSON {
+			logs, err = r.logsJSONUnmarshaler.UnmarshalLogs(data)
+		} else {
+			// Default to protobuf (application/x-protobuf or empty)
+			logs, err = r.logsUnmarshaler.UnmarshalLogs(data)
+		}
+		if err != nil {
+			err = decodeError{err}
+		}
 	}
 
 	if err != nil {
 		r.obsrecv.EndLogsOp(ctx, contentType, 0, err)
-		r.logger.Error("failed to unmarshal logs",
+		r.logger.Error("failed to decode logs",
 			zap.String("subject", msg.Subject()),
 			zap.String("content_type", contentType),
+			zap.String("content_encoding", msg.Headers().Get(internalnats.HeaderContentEncoding)),
 			zap.Error(err),
 		)
 		return err
 	}
 
+	if r.logsCoalescer != nil {
+		// Counted by the coalescer once the merged data is consumed
+		r.obsrecv.EndLogsOp(ctx, contentType, 0, nil)
+		r.logsCoalescer.add(r.coalesced(msg), contentType, logs)
+		return nil
+	}
+
 	logCount := logs.LogRecordCount()
-	err = r.logsConsumer.ConsumeLogs(ctx, logs)
+	consumeCtx, stop := r.keepInProgress(ctx, r.config.Logs.JetStream, msg)
+	err = r.logsConsumer.ConsumeLogs(consumeCtx, logs)
+	stop()
 	r.obsrecv.EndLogsOp(ctx, contentType, logCount, err)
 
 	if err != nil {
 		return receiverError{
 			err:    err,
+			items:  logCount,
 			fields: []zap.Field{zap.String("subject", msg.Subject())},
 		}
 	}
+	r.releaseClaimCheck(ctx, msg)
 	return nil
 }
 
+// clientContext returns ctx with the message headers as client metadata
+// if include_metadata is enabled. Reserved headers, which carry protocol and
+// transport details such as Nats-Msg-Id or the claim-check reference, are left out.
+func (r *natsReceiver) clientContext(ctx context.Context, msg otelnats.MessageCore) context.Context {
+	if !r.config.IncludeMetadata {
+		return ctx
+	}
+	md := make(map[string][]string, len(msg.Headers()))
+	for name, values := range msg.Headers() {
+		if internalnats.ValidateHeaderName(name) == nil {
+			md[name] = values
+		}
+	}
+	info := client.FromContext(ctx)
+	info.Metadata = client.NewMetadata(md)
+	return client.NewContext(ctx, info)
+}
+
+// payload returns the message data, fetched from Object Store for claim-check
+// references and decompressed according to its Content-Encoding header.
+// Decompression failures are returned as decodeError, fetch failures as is.
+func (r *natsReceiver) payload(ctx context.Context, msg otelnats.MessageCore) ([]byte, error) {
+	data := msg.Data()
+	if bucket, object, ok := claimCheckReference(msg.Headers()); ok {
+		var err error
+		if data, err = r.claimCheck.fetch(ctx, bucket, object); err != nil {
+			return nil, err
+		}
+	}
+	data, err := r.decompressor.Decompress(msg.Headers().Get(internalnats.HeaderContentEncoding), data)
+	if err != nil {
+		return nil, decodeError{err}
+	}
+	return data, nil
+}
+
+// releaseClaimCheck deletes the object referenced by a consumed claim-check
+// message if configured. Failures are logged only: the data was delivered,
+// and the bucket TTL eventually expires the object.
+func (r *natsReceiver) releaseClaimCheck(ctx context.Context, msg otelnats.MessageCore) {
+	bucket, object, ok := claimCheckReference(msg.Headers())
+	if !ok || !r.config.ClaimCheck.Delete {
+		return
+	}
+	if err := r.claimCheck.delete(ctx, bucket, object); err != nil {
+		r.logger.Warn("failed to delete claim-check object",
+			zap.String("bucket", bucket),
+			zap.String("object", object),
+			zap.Error(err),
+		)
+	}
+}
+
 func (r *natsReceiver) handleError(err error) {
 	level := zap.ErrorLevel
 	fields := []zap.Field{zap.Error(err)}
@@ -356,8 +664,23 @@ func (r *natsReceiver) handleError(err error) {
 	r.logger.Log(level, "NATS receiver error", fields...)
 }
 
+// decodeError is returned for a payload that cannot be decompressed or
+// unmarshaled, which no redelivery will fix.
+type decodeError struct {
+	err error
+}
+
+func (e decodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e decodeError) Unwrap() error {
+	return e.err
+}
+
 type receiverError struct {
 	err    error
+	items  int // spans, data points or log records in the failed batch
 	fields []zap.Field
 }
 
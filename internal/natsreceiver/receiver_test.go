@@ -2,21 +2,39 @@ package natsreceiver
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/mikluko/otelnats"
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/client"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
 	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config/configcompression"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/consumer/consumererror"
 	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/extension/extensiontest"
 	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/receiver"
 	"go.opentelemetry.io/collector/receiver/receivertest"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 
 	"github.com/mikluko/otelnats-collector/internal/metadata"
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+	"github.com/mikluko/otelnats-collector/internal/natsextension"
 	"github.com/mikluko/otelnats-collector/internal/testutil"
 )
 
@@ -288,3 +306,1289 @@ func TestE2E_MetricsOnlyReceiver_DoesNotSubscribeToOtherSignals(t *testing.T) {
 
 	assert.Equal(t, 1, sink.DataPointCount())
 }
+
+func TestE2E_ReceiveCompressedLogs(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	sink := &consumertest.LogsSink{}
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.QueueGroup = ""
+	cfg.MaxDecompressedSize = 4096
+
+	set := receivertest.NewNopSettings(metadata.Type)
+	rcv, err := factory.CreateLogs(ctx, set, cfg, sink)
+	require.NoError(t, err)
+
+	err = rcv.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer rcv.Shutdown(ctx)
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	publish := func(body string, compression configcompression.Type) {
+		logs := plog.NewLogs()
+		logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(body)
+
+		data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+		require.NoError(t, err)
+		data, err = internalnats.Compress(compression, data)
+		require.NoError(t, err)
+
+		headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+		headers.Set(internalnats.HeaderContentEncoding, string(compression))
+		require.NoError(t, nc.PublishMsg(&nats.Msg{Subject: "test.logs", Data: data, Header: headers}))
+	}
+
+	// Oversized payload decompresses beyond the cap and must be rejected
+	publish(strings.Repeat("x", 8192), configcompression.TypeZstd)
+	for _, compression := range []configcompression.Type{
+		configcompression.TypeGzip,
+		configcompression.TypeZstd,
+		configcompression.TypeSnappy,
+	} {
+		publish("compressed with "+string(compression), compression)
+	}
+	require.NoError(t, nc.Flush())
+
+	require.Eventually(t, func() bool {
+		return sink.LogRecordCount() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var bodies []string
+	for _, ld := range sink.AllLogs() {
+		bodies = append(bodies, ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+	}
+	assert.Equal(t, []string{
+		"compressed with gzip",
+		"compressed with zstd",
+		"compressed with snappy",
+	}, bodies)
+}
+
+func TestE2E_ReceiveClaimCheckLogs(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	sink := &consumertest.LogsSink{}
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.QueueGroup = ""
+	cfg.ClaimCheck.Buckets = []string{"otel-payloads"}
+	cfg.ClaimCheck.Delete = true
+
+	set := receivertest.NewNopSettings(metadata.Type)
+	rcv, err := factory.CreateLogs(ctx, set, cfg, sink)
+	require.NoError(t, err)
+
+	err = rcv.Start(ctx, componenttest.NewNopHost())
+	require.NoError(t, err)
+	defer rcv.Shutdown(ctx)
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	store, err := js.CreateObjectStore(ctx, jetstream.ObjectStoreConfig{Bucket: "otel-payloads"})
+	require.NoError(t, err)
+
+	// Store a gzip-compressed payload and publish a reference to it
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("stored")
+	data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+	require.NoError(t, err)
+	data, err = internalnats.Compress(configcompression.TypeGzip, data)
+	require.NoError(t, err)
+	_, err = store.PutBytes(ctx, "payload-1", data)
+	require.NoError(t, err)
+
+	headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+	headers.Set(internalnats.HeaderContentEncoding, "gzip")
+	headers.Set(internalnats.HeaderClaimCheckBucket, "otel-payloads")
+	headers.Set(internalnats.HeaderClaimCheckObject, "payload-1")
+	require.NoError(t, nc.PublishMsg(&nats.Msg{Subject: "test.logs", Header: headers}))
+
+	require.Eventually(t, func() bool {
+		return sink.LogRecordCount() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "stored", sink.AllLogs()[0].ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+
+	// The object is deleted once consumed
+	require.Eventually(t, func() bool {
+		_, err := store.GetInfo(ctx, "payload-1")
+		return errors.Is(err, jetstream.ErrObjectNotFound)
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestE2E_ReceiveLogsSharedConnection(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	// Start the connection extension
+	extID := component.MustNewIDWithName("nats", "main")
+	extFactory := natsextension.NewFactory()
+	extCfg := extFactory.CreateDefaultConfig().(*natsextension.Config)
+	extCfg.URL = ns.ClientURL()
+	ext, err := extFactory.Create(ctx, extensiontest.NewNopSettings(metadata.Type), extCfg)
+	require.NoError(t, err)
+	host := testutil.NewHost(map[component.ID]component.Component{extID: ext})
+	require.NoError(t, ext.Start(ctx, host))
+	defer ext.Shutdown(ctx)
+
+	sink := &consumertest.LogsSink{}
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.Connection = &extID
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.QueueGroup = ""
+
+	rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(ctx, host))
+
+	// Publish through the shared connection itself
+	conn := ext.(internalnats.ConnectionExtension).Conn()
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("shared")
+	data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+	require.NoError(t, err)
+	headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+	require.NoError(t, conn.PublishMsg(&nats.Msg{Subject: "test.logs", Data: data, Header: headers}))
+
+	require.Eventually(t, func() bool {
+		return sink.LogRecordCount() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Shutting down the receiver leaves the shared connection open
+	require.NoError(t, rcv.Shutdown(ctx))
+	assert.True(t, conn.IsConnected())
+}
+
+func TestE2E_ReceiveLogsProvisionedConsumer(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "OTEL",
+		Subjects: []string{"test.>"},
+	})
+	require.NoError(t, err)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.JetStream = &JetStreamConfig{
+		Stream:    "OTEL",
+		Consumer:  "otel-logs",
+		Provision: &ConsumerProvisionConfig{Policy: internalnats.ProvisionVerifyOnly},
+	}
+
+	// verify_only refuses to start without the consumer
+	rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	err = rcv.Start(ctx, componenttest.NewNopHost())
+	require.ErrorContains(t, err, `consumer "otel-logs" on stream "OTEL" does not exist`)
+	require.NoError(t, rcv.Shutdown(ctx))
+
+	sink := &consumertest.LogsSink{}
+	cfg.Logs.JetStream.Provision.Policy = internalnats.ProvisionCreateOnly
+	rcv, err = factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+	defer rcv.Shutdown(ctx)
+
+	consumer, err := js.Consumer(ctx, "OTEL", "otel-logs")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test.logs"}, consumer.CachedInfo().Config.FilterSubjects)
+
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("provisioned")
+	data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+	require.NoError(t, err)
+	headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+	_, err = js.PublishMsg(ctx, &nats.Msg{Subject: "test.logs", Data: data, Header: headers})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return sink.LogRecordCount() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestE2E_ReceiveLogsStartPosition(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "OTEL",
+		Subjects: []string{"test.>"},
+	})
+	require.NoError(t, err)
+
+	// Backlog of sequences 1 to 5, with start marking the third message
+	var start time.Time
+	for i := range 5 {
+		if i == 2 {
+			time.Sleep(20 * time.Millisecond)
+			start = time.Now()
+			time.Sleep(20 * time.Millisecond)
+		}
+		logs := plog.NewLogs()
+		logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(fmt.Sprintf("log-%d", i))
+		data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+		require.NoError(t, err)
+		headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+		_, err = js.PublishMsg(ctx, &nats.Msg{Subject: "test.logs", Data: data, Header: headers})
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name string
+		js   JetStreamConfig
+		want []string
+	}{
+		{
+			name: "all",
+			want: []string{"log-0", "log-1", "log-2", "log-3", "log-4"},
+		},
+		{
+			name: "last",
+			js:   JetStreamConfig{DeliverPolicy: "last"},
+			want: []string{"log-4"},
+		},
+		{
+			name: "by_start_sequence",
+			js:   JetStreamConfig{DeliverPolicy: "by_start_sequence", OptStartSeq: 4},
+			want: []string{"log-3", "log-4"},
+		},
+		{
+			name: "by_start_time",
+			js:   JetStreamConfig{DeliverPolicy: "by_start_time", OptStartTime: start},
+			want: []string{"log-2", "log-3", "log-4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &consumertest.LogsSink{}
+			factory := NewFactory()
+			cfg := factory.CreateDefaultConfig().(*Config)
+			cfg.ClientConfig.URL = ns.ClientURL()
+			cfg.Logs.Subject = "test.logs"
+			cfg.Logs.JetStream = &tt.js
+			cfg.Logs.JetStream.Stream = "OTEL"
+			cfg.Logs.JetStream.Consumer = "otel-logs-" + tt.name
+			require.NoError(t, cfg.Validate())
+
+			rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, sink)
+			require.NoError(t, err)
+			require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+			defer rcv.Shutdown(ctx)
+
+			cons, err := js.Consumer(ctx, "OTEL", cfg.Logs.JetStream.Consumer)
+			require.NoError(t, err)
+			require.Eventually(t, func() bool {
+				info, err := cons.Info(ctx)
+				return err == nil && info.NumPending == 0 && info.NumAckPending == 0 && sink.LogRecordCount() == len(tt.want)
+			}, 5*time.Second, 10*time.Millisecond)
+
+			var got []string
+			for _, ld := range sink.AllLogs() {
+				got = append(got, ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestE2E_ReceiveLogsRequestReply(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	records.AppendEmpty().Body().SetStr("first")
+	records.AppendEmpty().Body().SetStr("second")
+	data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+	require.NoError(t, err)
+
+	request := func(t *testing.T, next consumer.Logs, data []byte) *nats.Msg {
+		factory := NewFactory()
+		cfg := factory.CreateDefaultConfig().(*Config)
+		cfg.ClientConfig.URL = ns.ClientURL()
+		cfg.Logs.Subject = "test.logs"
+		cfg.Logs.RequestReply = true
+
+		rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, next)
+		require.NoError(t, err)
+		require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+		defer func() { require.NoError(t, rcv.Shutdown(ctx)) }()
+
+		msg := &nats.Msg{
+			Subject: "test.logs",
+			Data:    data,
+			Header:  otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil),
+		}
+		reply, err := nc.RequestMsg(msg, 5*time.Second)
+		require.NoError(t, err)
+		return reply
+	}
+	partialSuccess := func(t *testing.T, reply *nats.Msg) plogotlp.ExportPartialSuccess {
+		resp := plogotlp.NewExportResponse()
+		require.NoError(t, resp.UnmarshalProto(reply.Data))
+		return resp.PartialSuccess()
+	}
+
+	t.Run("success", func(t *testing.T) {
+		sink := &consumertest.LogsSink{}
+		reply := request(t, sink, data)
+		assert.Empty(t, reply.Header.Get(internalnats.HeaderExportError))
+		assert.Equal(t, otelnats.ContentTypeProtobuf, reply.Header.Get(otelnats.HeaderContentType))
+		assert.Equal(t, int64(0), partialSuccess(t, reply).RejectedLogRecords())
+		assert.Equal(t, 2, sink.LogRecordCount())
+	})
+
+	t.Run("permanent error", func(t *testing.T) {
+		reply := request(t, consumertest.NewErr(consumererror.NewPermanent(errors.New("invalid record"))), data)
+		assert.Empty(t, reply.Header.Get(internalnats.HeaderExportError))
+		assert.Equal(t, int64(2), partialSuccess(t, reply).RejectedLogRecords())
+		assert.Contains(t, partialSuccess(t, reply).ErrorMessage(), "invalid record")
+	})
+
+	t.Run("retryable error", func(t *testing.T) {
+		reply := request(t, consumertest.NewErr(errors.New("queue is full")), data)
+		assert.Equal(t, "queue is full", reply.Header.Get(internalnats.HeaderExportError))
+		assert.Empty(t, reply.Header.Get(internalnats.HeaderExportErrorPermanent))
+	})
+
+	t.Run("undecodable request", func(t *testing.T) {
+		reply := request(t, consumertest.NewNop(), []byte("not protobuf"))
+		assert.NotEmpty(t, reply.Header.Get(internalnats.HeaderExportError))
+		assert.Equal(t, "true", reply.Header.Get(internalnats.HeaderExportErrorPermanent))
+	})
+}
+
+func TestE2E_ReceiveLogsIncludeMetadata(t *testing.T) {
+	ns := testutil.StartEmbeddedNATS(t)
+	ctx := context.Background()
+
+	sink := &consumertest.LogsSink{}
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.IncludeMetadata = true
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.QueueGroup = ""
+
+	rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+	defer rcv.Shutdown(ctx)
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+	require.NoError(t, err)
+	headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+	headers.Set("X-Tenant-Id", "acme")
+	headers.Set(jetstream.MsgIDHeader, "batch-1")
+	headers.Set(internalnats.HeaderDeadLetterError, "replayed")
+	require.NoError(t, nc.PublishMsg(&nats.Msg{Subject: "test.logs", Data: data, Header: headers}))
+
+	require.Eventually(t, func() bool {
+		return sink.LogRecordCount() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	info := client.FromContext(sink.Contexts()[0])
+	assert.Equal(t, []string{"acme"}, info.Metadata.Get("x-tenant-id"))
+
+	// Protocol and transport headers are not client metadata
+	for _, name := range []string{otelnats.HeaderContentType, otelnats.HeaderOtelSignal, jetstream.MsgIDHeader, internalnats.HeaderDeadLetterError} {
+		assert.Empty(t, info.Metadata.Get(name), name)
+	}
+}
+
+func TestE2E_ReceiveLogsPartitions(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	factory := NewFactory()
+	newReceiver := func(sink *consumertest.LogsSink) receiver.Logs {
+		cfg := factory.CreateDefaultConfig().(*Config)
+		cfg.ClientConfig.URL = ns.ClientURL()
+		cfg.Logs.Subject = "test.logs"
+		cfg.Logs.Partitions = &PartitionsConfig{Count: 4, Bucket: "otel-members", TTL: time.Second}
+		require.NoError(t, cfg.Validate())
+		rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, sink)
+		require.NoError(t, err)
+		require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+		return rcv
+	}
+
+	first, second := &consumertest.LogsSink{}, &consumertest.LogsSink{}
+	rcv1 := newReceiver(first)
+	rcv2 := newReceiver(second)
+	defer rcv2.Shutdown(ctx)
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+	require.NoError(t, err)
+	publishAll := func() {
+		for p := 0; p < 4; p++ {
+			require.NoError(t, nc.PublishMsg(&nats.Msg{
+				Subject: fmt.Sprintf("test.logs.%d", p),
+				Data:    data,
+				Header:  otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil),
+			}))
+		}
+	}
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	kv, err := js.KeyValue(ctx, "otel-members")
+	require.NoError(t, err)
+
+	// Once both receivers are members, each partition is consumed once. Either
+	// may be assigned all of them, since member keys are random.
+	require.Eventually(t, func() bool {
+		first.Reset()
+		second.Reset()
+		publishAll()
+		require.NoError(t, nc.Flush())
+		time.Sleep(50 * time.Millisecond)
+		members, err := kv.Keys(ctx)
+		return err == nil && len(members) == 2 &&
+			first.LogRecordCount()+second.LogRecordCount() == 4
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// The second receiver takes over when the first leaves
+	require.NoError(t, rcv1.Shutdown(ctx))
+	require.Eventually(t, func() bool {
+		second.Reset()
+		publishAll()
+		require.NoError(t, nc.Flush())
+		time.Sleep(50 * time.Millisecond)
+		return second.LogRecordCount() == 4
+	}, 5*time.Second, 100*time.Millisecond)
+}
+
+func TestE2E_ReceiveLogsPartitionsHandover(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	factory := NewFactory()
+	newReceiver := func(sink *consumertest.LogsSink) receiver.Logs {
+		cfg := factory.CreateDefaultConfig().(*Config)
+		cfg.ClientConfig.URL = ns.ClientURL()
+		cfg.Logs.Subject = "test.logs"
+		cfg.Logs.Partitions = &PartitionsConfig{Count: 8, Bucket: "otel-members", TTL: time.Second}
+		rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, sink)
+		require.NoError(t, err)
+		require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+		return rcv
+	}
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	first, second := &consumertest.LogsSink{}, &consumertest.LogsSink{}
+	rcv1 := newReceiver(first)
+	defer rcv1.Shutdown(ctx)
+
+	// Publish to every partition while the second member joins, until the
+	// first one has surely noticed it and released the partitions it lost
+	published := 0
+	publish := func() {
+		for p := range 8 {
+			logs := plog.NewLogs()
+			logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(strconv.Itoa(published))
+			data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+			require.NoError(t, err)
+			require.NoError(t, nc.PublishMsg(&nats.Msg{
+				Subject: fmt.Sprintf("test.logs.%d", p),
+				Data:    data,
+				Header:  otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil),
+			}))
+			published++
+		}
+		require.NoError(t, nc.Flush())
+	}
+	publish()
+	rcv2 := newReceiver(second)
+	defer rcv2.Shutdown(ctx)
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); {
+		publish()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	require.Eventually(t, func() bool {
+		return first.LogRecordCount()+second.LogRecordCount() >= published
+	}, 5*time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	// Each message is consumed by a single member, even while both own a partition
+	seen := map[string]int{}
+	for _, sink := range []*consumertest.LogsSink{first, second} {
+		for _, ld := range sink.AllLogs() {
+			seen[ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str()]++
+		}
+	}
+	assert.Len(t, seen, published)
+	for body, n := range seen {
+		assert.Equal(t, 1, n, "message %s consumed %d times", body, n)
+	}
+}
+
+func TestE2E_ReceiveLogsPartitionsJetStream(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "OTEL_LOGS",
+		Subjects: []string{"test.logs.*"},
+		Storage:  jetstream.MemoryStorage,
+	})
+	require.NoError(t, err)
+
+	sink := &consumertest.LogsSink{}
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.JetStream = &JetStreamConfig{Stream: "OTEL_LOGS", Consumer: "otel"}
+	cfg.Logs.Partitions = &PartitionsConfig{Count: 2, Bucket: "otel-members"}
+	require.NoError(t, cfg.Validate())
+
+	rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+	defer rcv.Shutdown(ctx)
+
+	// Each partition has its own durable consumer
+	for p := 0; p < 2; p++ {
+		consumer, err := js.Consumer(ctx, "OTEL_LOGS", fmt.Sprintf("otel-%d", p))
+		require.NoError(t, err)
+		assert.Equal(t, []string{fmt.Sprintf("test.logs.%d", p)}, consumer.CachedInfo().Config.FilterSubjects)
+	}
+
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+	data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+	require.NoError(t, err)
+	for p := 0; p < 2; p++ {
+		_, err := js.PublishMsg(ctx, &nats.Msg{
+			Subject: fmt.Sprintf("test.logs.%d", p),
+			Data:    data,
+			Header:  otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil),
+		})
+		require.NoError(t, err)
+	}
+	require.Eventually(t, func() bool {
+		return sink.LogRecordCount() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestE2E_ReceiveMetricsSnapshot(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:              "OTEL_SNAPSHOT",
+		Subjects:          []string{"test.snapshot.>"},
+		MaxMsgsPerSubject: 1,
+	})
+	require.NoError(t, err)
+
+	publish := func(series string, value float64) {
+		md := pmetric.NewMetrics()
+		m := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+		m.SetName("queue.depth")
+		m.SetEmptyGauge().DataPoints().AppendEmpty().SetDoubleValue(value)
+		data, err := (&pmetric.ProtoMarshaler{}).MarshalMetrics(md)
+		require.NoError(t, err)
+		headers := otelnats.BuildHeaders(ctx, otelnats.SignalMetrics, otelnats.EncodingProtobuf, nil)
+		_, err = js.PublishMsg(ctx, &nats.Msg{Subject: "test.snapshot.queue.depth." + series, Data: data, Header: headers})
+		require.NoError(t, err)
+	}
+	// Only the last value of each series survives in the stream
+	publish("a", 1)
+	publish("a", 2)
+	publish("b", 3)
+
+	sink := &consumertest.MetricsSink{}
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Metrics.Subject = "test.snapshot"
+	cfg.Metrics.Snapshot = true
+	cfg.Metrics.JetStream = &JetStreamConfig{Stream: "OTEL_SNAPSHOT"}
+	require.NoError(t, cfg.Validate())
+
+	rcv, err := factory.CreateMetrics(ctx, receivertest.NewNopSettings(metadata.Type), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+	defer rcv.Shutdown(ctx)
+
+	require.Eventually(t, func() bool {
+		return sink.DataPointCount() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Live updates follow the snapshot
+	publish("a", 4)
+	require.Eventually(t, func() bool {
+		return sink.DataPointCount() == 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var values []float64
+	for _, md := range sink.AllMetrics() {
+		values = append(values, md.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Gauge().DataPoints().At(0).DoubleValue())
+	}
+	assert.Equal(t, []float64{2, 3, 4}, values)
+}
+
+func TestE2E_ReceiveLogsTelemetry(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "OTEL",
+		Subjects: []string{"test.>"},
+	})
+	require.NoError(t, err)
+	_, err = js.CreateConsumer(ctx, "OTEL", jetstream.ConsumerConfig{
+		Durable:       "otel-logs",
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		FilterSubject: "test.logs",
+	})
+	require.NoError(t, err)
+
+	tel := componenttest.NewTelemetry()
+	defer tel.Shutdown(ctx)
+
+	// The first delivery fails downstream and is redelivered
+	sink := &consumertest.LogsSink{}
+	var calls int
+	next, err := consumer.NewLogs(func(ctx context.Context, ld plog.Logs) error {
+		calls++
+		if calls == 1 {
+			return errors.New("downstream unavailable")
+		}
+		return sink.ConsumeLogs(ctx, ld)
+	})
+	require.NoError(t, err)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	// A short ack_wait also shortens each fetch, which records its fill on completion
+	cfg.Logs.JetStream = &JetStreamConfig{Stream: "OTEL", Consumer: "otel-logs", AckWait: time.Second, RateLimit: 1000, RateBurst: 10}
+
+	set := receivertest.NewNopSettings(metadata.Type)
+	set.TelemetrySettings = tel.NewTelemetrySettings()
+	rcv, err := factory.CreateLogs(ctx, set, cfg, next)
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+	defer rcv.Shutdown(ctx)
+
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("metered")
+	data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+	require.NoError(t, err)
+	headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+	_, err = js.PublishMsg(ctx, &nats.Msg{Subject: "test.logs", Data: data, Header: headers})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return sink.LogRecordCount() == 1
+	}, 10*time.Second, 10*time.Millisecond)
+
+	acks := map[string]int64{}
+	require.Eventually(t, func() bool {
+		m, err := tel.GetMetric("otelcol_receiver_nats_acknowledgements")
+		if err != nil {
+			return false
+		}
+		for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+			typ, _ := dp.Attributes.Value("type")
+			acks[typ.AsString()] = dp.Value
+		}
+		return acks["ack"] == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, map[string]int64{"ack": 1, "nak": 1}, acks)
+
+	m, err := tel.GetMetric("otelcol_receiver_nats_redelivered_messages")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), m.Data.(metricdata.Sum[int64]).DataPoints[0].Value)
+
+	require.Eventually(t, func() bool {
+		m, err := tel.GetMetric("otelcol_receiver_nats_fetch_fill_ratio")
+		return err == nil && m.Data.(metricdata.Histogram[float64]).DataPoints[0].Count > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The limiter is waited for before each fetch
+	m, err = tel.GetMetric("otelcol_receiver_nats_rate_limit_wait")
+	require.NoError(t, err)
+	assert.Greater(t, m.Data.(metricdata.Histogram[float64]).DataPoints[0].Count, uint64(1))
+}
+
+func TestE2E_ReceiveLogsDeferredConnect(t *testing.T) {
+	port := testutil.FreePort(t)
+	ctx := context.Background()
+
+	sink := &consumertest.LogsSink{}
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = fmt.Sprintf("nats://127.0.0.1:%d", port)
+	cfg.ReconnectWait = 10 * time.Millisecond
+	cfg.RetryOnFailedInitialConnect = true
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.QueueGroup = ""
+
+	// Start succeeds without a server and reports the missing connection
+	host := testutil.NewHost(nil)
+	rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(ctx, host))
+	defer rcv.Shutdown(ctx)
+	assert.Equal(t, []componentstatus.Status{componentstatus.StatusRecoverableError}, host.Statuses())
+
+	ns := testutil.StartEmbeddedNATSOnPort(t, port)
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	logs := plog.NewLogs()
+	logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("deferred")
+	data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+	require.NoError(t, err)
+	headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+
+	// The receiver subscribes once connected; publish until it does
+	require.Eventually(t, func() bool {
+		require.NoError(t, nc.PublishMsg(&nats.Msg{Subject: "test.logs", Data: data, Header: headers}))
+		return sink.LogRecordCount() > 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		statuses := host.Statuses()
+		return statuses[len(statuses)-1] == componentstatus.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestE2E_ReceiveLogsDeadLetter(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "OTEL",
+		Subjects: []string{"test.>"},
+	})
+	require.NoError(t, err)
+	dlq, err := js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "DLQ",
+		Subjects: []string{"dlq.>"},
+	})
+	require.NoError(t, err)
+
+	// The pipeline rejects one of the records every time
+	sink := &consumertest.LogsSink{}
+	next, err := consumer.NewLogs(func(ctx context.Context, ld plog.Logs) error {
+		if ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str() == "rejected" {
+			return errors.New("rejected downstream")
+		}
+		return sink.ConsumeLogs(ctx, ld)
+	})
+	require.NoError(t, err)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.JetStream = &JetStreamConfig{Stream: "OTEL", Consumer: "otel-logs", MaxDeliver: 3}
+	cfg.Logs.DeadLetter = &DeadLetterConfig{Subject: "dlq.logs", Stream: "DLQ"}
+	cfg.ClaimCheck.Buckets = []string{"otel-payloads"}
+	cfg.MaxDecompressedSize = 1024
+
+	rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, next)
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+	defer rcv.Shutdown(ctx)
+
+	publish := func(data []byte) {
+		headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+		headers.Set("X-Tenant", "acme")
+		_, err := js.PublishMsg(ctx, &nats.Msg{Subject: "test.logs", Data: data, Header: headers}, jetstream.WithExpectStream("OTEL"))
+		require.NoError(t, err)
+	}
+	marshal := func(body string) []byte {
+		logs := plog.NewLogs()
+		logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(body)
+		data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+		require.NoError(t, err)
+		return data
+	}
+	publish([]byte{0xff, 0xff, 0xff})
+	publish(marshal("rejected"))
+	publish(marshal("accepted"))
+
+	require.Eventually(t, func() bool {
+		info, err := dlq.Info(ctx)
+		return err == nil && info.State.Msgs == 2
+	}, 10*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, sink.LogRecordCount())
+
+	// Dead-lettered messages are terminated, not redelivered
+	cons, err := js.Consumer(ctx, "OTEL", "otel-logs")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		info, err := cons.Info(ctx)
+		return err == nil && info.NumPending == 0 && info.NumAckPending == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	undecodable, err := dlq.GetMsg(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xff, 0xff, 0xff}, undecodable.Data)
+	assert.Equal(t, "acme", undecodable.Header.Get("X-Tenant"))
+	assert.Equal(t, otelnats.SignalLogs, undecodable.Header.Get(otelnats.HeaderOtelSignal))
+	assert.NotEmpty(t, undecodable.Header.Get(internalnats.HeaderDeadLetterError))
+	assert.Equal(t, "test.logs", undecodable.Header.Get(internalnats.HeaderDeadLetterSubject))
+	assert.Equal(t, "1", undecodable.Header.Get(internalnats.HeaderDeadLetterDeliveries))
+
+	rejected, err := dlq.GetMsg(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, marshal("rejected"), rejected.Data)
+	assert.Equal(t, errMaxDeliveries, rejected.Header.Get(internalnats.HeaderDeadLetterError))
+	assert.Equal(t, "3", rejected.Header.Get(internalnats.HeaderDeadLetterDeliveries))
+
+	// A claim-check object that cannot be fetched is redelivered, not dead-lettered as undecodable
+	_, err = js.CreateObjectStore(ctx, jetstream.ObjectStoreConfig{Bucket: "otel-payloads"})
+	require.NoError(t, err)
+	headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+	headers.Set(internalnats.HeaderClaimCheckBucket, "otel-payloads")
+	headers.Set(internalnats.HeaderClaimCheckObject, "missing")
+	_, err = js.PublishMsg(ctx, &nats.Msg{Subject: "test.logs", Header: headers}, jetstream.WithExpectStream("OTEL"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		info, err := dlq.Info(ctx)
+		return err == nil && info.State.Msgs == 3
+	}, 10*time.Second, 10*time.Millisecond)
+	missing, err := dlq.GetMsg(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, errMaxDeliveries, missing.Header.Get(internalnats.HeaderDeadLetterError))
+	assert.Equal(t, "3", missing.Header.Get(internalnats.HeaderDeadLetterDeliveries))
+
+	// References to other buckets and to objects above max_decompressed_size are never fetched
+	other, err := js.CreateObjectStore(ctx, jetstream.ObjectStoreConfig{Bucket: "other"})
+	require.NoError(t, err)
+	_, err = other.PutBytes(ctx, "payload", marshal("elsewhere"))
+	require.NoError(t, err)
+	headers.Set(internalnats.HeaderClaimCheckBucket, "other")
+	headers.Set(internalnats.HeaderClaimCheckObject, "payload")
+	_, err = js.PublishMsg(ctx, &nats.Msg{Subject: "test.logs", Header: headers}, jetstream.WithExpectStream("OTEL"))
+	require.NoError(t, err)
+
+	store, err := js.ObjectStore(ctx, "otel-payloads")
+	require.NoError(t, err)
+	_, err = store.PutBytes(ctx, "large", make([]byte, 2048))
+	require.NoError(t, err)
+	headers.Set(internalnats.HeaderClaimCheckBucket, "otel-payloads")
+	headers.Set(internalnats.HeaderClaimCheckObject, "large")
+	_, err = js.PublishMsg(ctx, &nats.Msg{Subject: "test.logs", Header: headers}, jetstream.WithExpectStream("OTEL"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		info, err := dlq.Info(ctx)
+		return err == nil && info.State.Msgs == 5
+	}, 10*time.Second, 10*time.Millisecond)
+	for seq, want := range map[uint64]string{4: `"other"`, 5: "exceeds 1024 bytes"} {
+		msg, err := dlq.GetMsg(ctx, seq)
+		require.NoError(t, err)
+		assert.Contains(t, msg.Header.Get(internalnats.HeaderDeadLetterError), want)
+		assert.Equal(t, "1", msg.Header.Get(internalnats.HeaderDeadLetterDeliveries))
+	}
+	assert.Equal(t, 1, sink.LogRecordCount())
+}
+
+func TestE2E_ReceiveLogsDeadLetterUnavailable(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "OTEL",
+		Subjects: []string{"test.>"},
+	})
+	require.NoError(t, err)
+	// The dead letter subject is stored by another stream, so publishing fails
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "OTHER",
+		Subjects: []string{"dlq.>"},
+	})
+	require.NoError(t, err)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.JetStream = &JetStreamConfig{Stream: "OTEL", Consumer: "otel-logs", Backoff: []time.Duration{300 * time.Millisecond}}
+	cfg.Logs.DeadLetter = &DeadLetterConfig{Subject: "dlq.logs", Stream: "DLQ"}
+
+	rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, consumertest.NewNop())
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+	defer rcv.Shutdown(ctx)
+
+	headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+	_, err = js.PublishMsg(ctx, &nats.Msg{Subject: "test.logs", Data: []byte{0xff, 0xff, 0xff}, Header: headers})
+	require.NoError(t, err)
+
+	// While the dead letter cannot be published, the message is redelivered
+	// after the backoff rather than right away
+	time.Sleep(time.Second)
+	cons, err := js.Consumer(ctx, "OTEL", "otel-logs")
+	require.NoError(t, err)
+	info, err := cons.Info(ctx)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Delivered.Consumer, uint64(4))
+
+	// Once the DLQ stream stores the subject, the next delivery is dead-lettered
+	require.NoError(t, js.DeleteStream(ctx, "OTHER"))
+	dlq, err := js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "DLQ",
+		Subjects: []string{"dlq.>"},
+	})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		info, err := dlq.Info(ctx)
+		return err == nil && info.State.Msgs == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestE2E_ReceiveLogsRedelivery(t *testing.T) {
+	tests := []struct {
+		name     string
+		js       JetStreamConfig
+		err      error
+		attempts int // deliveries after which the message is no longer redelivered
+		minGaps  []time.Duration
+	}{
+		{
+			name:     "nak with backoff",
+			js:       JetStreamConfig{Backoff: []time.Duration{200 * time.Millisecond, 400 * time.Millisecond}, MaxDeliver: 3},
+			err:      errors.New("backend unavailable"),
+			attempts: 3,
+			minGaps:  []time.Duration{200 * time.Millisecond, 400 * time.Millisecond},
+		},
+		{
+			name:     "timeout",
+			js:       JetStreamConfig{AckWait: time.Second, OnRetryableError: failureTimeout, MaxDeliver: 2},
+			err:      errors.New("backend unavailable"),
+			attempts: 2,
+			minGaps:  []time.Duration{900 * time.Millisecond}, // ack_wait runs from delivery, just before the call
+		},
+		{
+			name:     "term permanent",
+			js:       JetStreamConfig{OnPermanentError: failureTerm},
+			err:      consumererror.NewPermanent(errors.New("invalid data")),
+			attempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ns := testutil.StartEmbeddedNATSWithJetStream(t)
+			ctx := context.Background()
+
+			nc, err := nats.Connect(ns.ClientURL())
+			require.NoError(t, err)
+			defer nc.Close()
+
+			js, err := jetstream.New(nc)
+			require.NoError(t, err)
+			_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+				Name:     "OTEL",
+				Subjects: []string{"test.>"},
+			})
+			require.NoError(t, err)
+
+			var mu sync.Mutex
+			var calls []time.Time
+			next, err := consumer.NewLogs(func(context.Context, plog.Logs) error {
+				mu.Lock()
+				defer mu.Unlock()
+				calls = append(calls, time.Now())
+				return tt.err
+			})
+			require.NoError(t, err)
+
+			factory := NewFactory()
+			cfg := factory.CreateDefaultConfig().(*Config)
+			cfg.ClientConfig.URL = ns.ClientURL()
+			cfg.Logs.Subject = "test.logs"
+			cfg.Logs.JetStream = &tt.js
+			cfg.Logs.JetStream.Stream = "OTEL"
+			cfg.Logs.JetStream.Consumer = "otel-logs"
+
+			rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, next)
+			require.NoError(t, err)
+			require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+			defer rcv.Shutdown(ctx)
+
+			logs := plog.NewLogs()
+			logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("failing")
+			data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+			require.NoError(t, err)
+			headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+			_, err = js.PublishMsg(ctx, &nats.Msg{Subject: "test.logs", Data: data, Header: headers})
+			require.NoError(t, err)
+
+			// The message is settled for good after the last attempt
+			cons, err := js.Consumer(ctx, "OTEL", "otel-logs")
+			require.NoError(t, err)
+			require.Eventually(t, func() bool {
+				info, err := cons.Info(ctx)
+				return err == nil && info.NumPending == 0 && info.NumAckPending == 0 && info.NumRedelivered == 0
+			}, 10*time.Second, 10*time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+			require.Len(t, calls, tt.attempts)
+			for i, gap := range tt.minGaps {
+				assert.GreaterOrEqual(t, calls[i+1].Sub(calls[i]), gap, "redelivery %d", i+1)
+			}
+		})
+	}
+}
+
+func TestE2E_ReceiveLogsInProgress(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "OTEL",
+		Subjects: []string{"test.>"},
+	})
+	require.NoError(t, err)
+
+	// The first message outlasts ack_wait but completes in time, the second
+	// one hangs until the receiver gives up and succeeds on redelivery
+	var mu sync.Mutex
+	var bodies []string
+	var causes []error
+	next, err := consumer.NewLogs(func(ctx context.Context, ld plog.Logs) error {
+		body := ld.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str()
+		mu.Lock()
+		bodies = append(bodies, body)
+		hung := body == "hung" && len(causes) == 0
+		mu.Unlock()
+		switch {
+		case body == "slow":
+			time.Sleep(2500 * time.Millisecond)
+		case hung:
+			<-ctx.Done()
+			mu.Lock()
+			causes = append(causes, context.Cause(ctx))
+			mu.Unlock()
+			return ctx.Err()
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.JetStream = &JetStreamConfig{
+		Stream:     "OTEL",
+		Consumer:   "otel-logs",
+		AckWait:    time.Second,
+		InProgress: &InProgressConfig{MaxDuration: 4 * time.Second},
+	}
+
+	rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, next)
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+	defer rcv.Shutdown(ctx)
+
+	cons, err := js.Consumer(ctx, "OTEL", "otel-logs")
+	require.NoError(t, err)
+
+	// One at a time: messages queued behind a slow one are not kept in progress
+	for _, body := range []string{"slow", "hung"} {
+		logs := plog.NewLogs()
+		logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(body)
+		data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+		require.NoError(t, err)
+		headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+		_, err = js.PublishMsg(ctx, &nats.Msg{Subject: "test.logs", Data: data, Header: headers})
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			info, err := cons.Info(ctx)
+			return err == nil && info.NumPending == 0 && info.NumAckPending == 0
+		}, 15*time.Second, 50*time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"slow", "hung", "hung"}, bodies, "only the hung message is redelivered")
+	require.Len(t, causes, 1)
+	assert.ErrorIs(t, causes[0], errInProgressExpired)
+}
+
+func TestE2E_ReceiveLogsCoalesce(t *testing.T) {
+	ns := testutil.StartEmbeddedNATSWithJetStream(t)
+	ctx := context.Background()
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "OTEL",
+		Subjects: []string{"test.>"},
+	})
+	require.NoError(t, err)
+
+	// The first pipeline call fails, so that all of its messages are redelivered
+	var mu sync.Mutex
+	var sizes []int
+	consumed := map[string]int{}
+	next, err := consumer.NewLogs(func(_ context.Context, ld plog.Logs) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sizes = append(sizes, ld.LogRecordCount())
+		if len(sizes) == 1 {
+			return errors.New("backend unavailable")
+		}
+		for i := 0; i < ld.ResourceLogs().Len(); i++ {
+			body := ld.ResourceLogs().At(i).ScopeLogs().At(0).LogRecords().At(0).Body().Str()
+			consumed[body]++
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig().(*Config)
+	cfg.ClientConfig.URL = ns.ClientURL()
+	cfg.Logs.Subject = "test.logs"
+	cfg.Logs.JetStream = &JetStreamConfig{Stream: "OTEL", Consumer: "otel-logs"}
+	cfg.Logs.Coalesce = &CoalesceConfig{MaxMessages: 4, Timeout: 300 * time.Millisecond}
+
+	// Published before start, so that batches fill up
+	const count = 10
+	for i := range count {
+		logs := plog.NewLogs()
+		logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr(fmt.Sprintf("log-%d", i))
+		data, err := (&plog.ProtoMarshaler{}).MarshalLogs(logs)
+		require.NoError(t, err)
+		headers := otelnats.BuildHeaders(ctx, otelnats.SignalLogs, otelnats.EncodingProtobuf, nil)
+		_, err = js.PublishMsg(ctx, &nats.Msg{Subject: "test.logs", Data: data, Header: headers})
+		require.NoError(t, err)
+	}
+
+	rcv, err := factory.CreateLogs(ctx, receivertest.NewNopSettings(metadata.Type), cfg, next)
+	require.NoError(t, err)
+	require.NoError(t, rcv.Start(ctx, componenttest.NewNopHost()))
+	defer rcv.Shutdown(ctx)
+
+	cons, err := js.Consumer(ctx, "OTEL", "otel-logs")
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		n := len(consumed)
+		mu.Unlock()
+		info, err := cons.Info(ctx)
+		return n == count && err == nil && info.NumPending == 0 && info.NumAckPending == 0
+	}, 10*time.Second, 50*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Less(t, len(sizes), count, "messages are coalesced")
+	for _, size := range sizes {
+		assert.LessOrEqual(t, size, 4)
+	}
+	assert.Equal(t, 4, sizes[0])
+	for body, n := range consumed {
+		assert.Equal(t, 1, n, "%s consumed once", body)
+	}
+}
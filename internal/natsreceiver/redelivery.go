@@ -0,0 +1,83 @@
+package natsreceiver
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+)
+
+// Failure policies for messages the pipeline failed to consume.
+const (
+	failureNak     = "nak"
+	failureTimeout = "timeout"
+	failureTerm    = "term"
+)
+
+// configuresRedelivery reports whether c changes how messages the pipeline
+// failed to consume are redelivered, rather than negatively acknowledged.
+func (c *JetStreamConfig) configuresRedelivery() bool {
+	return c.MaxDeliver > 0 || len(c.Backoff) > 0 || c.OnRetryableError != "" || c.OnPermanentError != ""
+}
+
+// backoff returns the redelivery delay after a message failed on its
+// deliveries-th delivery.
+func (c *JetStreamConfig) backoff(deliveries uint64) time.Duration {
+	if len(c.Backoff) == 0 || deliveries == 0 {
+		return 0
+	}
+	return c.Backoff[min(deliveries, uint64(len(c.Backoff)))-1]
+}
+
+// failureAck returns the acknowledgement after the pipeline failed to
+// consume m with err, or nil to leave m to time out. Messages that do not
+// decode are dead-lettered if configured, and otherwise failed permanently.
+// The redelivery settings of the signal apply to the rest, and to messages
+// whose dead letter could not be published.
+func (j *jetStreamReceiver) failureAck(m *jetStreamMsg, err error) func(jetstream.Msg) error {
+	jsConfig := j.cfg.JetStream
+	n := deliveries(m.Msg)
+	delay := jsConfig.backoff(n)
+	redeliver := func(msg jetstream.Msg) error { return msg.NakWithDelay(delay) }
+
+	var derr decodeError
+	if errors.As(err, &derr) {
+		if j.deadLetter != nil {
+			return func(msg jetstream.Msg) error { return j.deadLetter.deadLetter(msg, err.Error(), redeliver) }
+		}
+		err = consumererror.NewPermanent(err)
+	}
+	var rerr receiverError
+	if errors.As(err, &rerr) {
+		err = rerr.err
+	}
+
+	if !jsConfig.configuresRedelivery() {
+		return jetstream.Msg.Nak
+	}
+	if jsConfig.MaxDeliver > 0 && n >= uint64(jsConfig.MaxDeliver) {
+		j.r.logger.Warn("giving up on message after max_deliver deliveries",
+			zap.String("subject", m.Subject()),
+			zap.Uint64("deliveries", n),
+		)
+		if j.deadLetter != nil {
+			return func(msg jetstream.Msg) error { return j.deadLetter.deadLetter(msg, errMaxDeliveries, redeliver) }
+		}
+		return jetstream.Msg.Term
+	}
+
+	policy := jsConfig.OnRetryableError
+	if consumererror.IsPermanent(err) {
+		policy = jsConfig.OnPermanentError
+	}
+	switch policy {
+	case failureTimeout:
+		return nil
+	case failureTerm:
+		return jetstream.Msg.Term
+	default:
+		return redeliver
+	}
+}
@@ -0,0 +1,19 @@
+package natsreceiver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJetStreamConfig_Backoff(t *testing.T) {
+	cfg := &JetStreamConfig{Backoff: []time.Duration{time.Second, 5 * time.Second, time.Minute}}
+	assert.Equal(t, time.Duration(0), cfg.backoff(0))
+	assert.Equal(t, time.Second, cfg.backoff(1))
+	assert.Equal(t, 5*time.Second, cfg.backoff(2))
+	assert.Equal(t, time.Minute, cfg.backoff(3))
+	assert.Equal(t, time.Minute, cfg.backoff(10), "the last delay repeats")
+
+	assert.Equal(t, time.Duration(0), (&JetStreamConfig{}).backoff(3))
+}
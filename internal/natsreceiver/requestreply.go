@@ -0,0 +1,188 @@
+package natsreceiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mikluko/otelnats"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	tracespb "go.opentelemetry.io/proto/otlp/trace/v1"
+
+	internalnats "github.com/mikluko/otelnats-collector/internal/nats"
+)
+
+// requestMessage adapts a core NATS request to the SDK message interface,
+// so that requests go through the same handlers as any other message.
+type requestMessage[T any] struct {
+	msg *nats.Msg
+}
+
+func (m *requestMessage[T]) Subject() string      { return m.msg.Subject }
+func (m *requestMessage[T]) Data() []byte         { return m.msg.Data }
+func (m *requestMessage[T]) Headers() nats.Header { return m.msg.Header }
+func (m *requestMessage[T]) Ack() error           { return nil }
+func (m *requestMessage[T]) Nak() error           { return nil }
+func (m *requestMessage[T]) Term() error          { return nil }
+
+func (m *requestMessage[T]) Signal() (*T, error) {
+	return unmarshalSignal[T](m.msg.Data, m.msg.Header)
+}
+
+// signal returns the signal this receiver instance consumes.
+func (r *natsReceiver) signal() string {
+	switch {
+	case r.tracesConsumer != nil:
+		return otelnats.SignalTraces
+	case r.metricsConsumer != nil:
+		return otelnats.SignalMetrics
+	default:
+		return otelnats.SignalLogs
+	}
+}
+
+// subscribeRequests subscribes to subject directly instead of through the
+// SDK receiver, which does not expose the reply subject of a message.
+// Requests are handled one at a time; scale out with a queue group.
+func (r *natsReceiver) subscribeRequests(subject, queueGroup string) error {
+	var err error
+	if queueGroup != "" {
+		r.requestSub, err = r.conn.QueueSubscribe(subject, queueGroup, r.handleRequest)
+	} else {
+		r.requestSub, err = r.conn.Subscribe(subject, r.handleRequest)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to %q: %w", subject, err)
+	}
+	// Flush to ensure the subscription is registered with the server
+	return r.conn.Flush()
+}
+
+// handleRequest consumes a request and replies with the outcome.
+func (r *natsReceiver) handleRequest(msg *nats.Msg) {
+	ctx := context.Background()
+	signal := r.signal()
+
+	var err error
+	switch got := msg.Header.Get(otelnats.HeaderOtelSignal); {
+	case got != signal:
+		err = fmt.Errorf("%w: %q", otelnats.ErrUnknownSignal, got)
+	case signal == otelnats.SignalTraces:
+		err = r.handleTracesMessage(ctx, &requestMessage[tracespb.TracesData]{msg: msg})
+	case signal == otelnats.SignalMetrics:
+		err = r.handleMetricsMessage(ctx, &requestMessage[metricspb.MetricsData]{msg: msg})
+	default:
+		err = r.handleLogsMessage(ctx, &requestMessage[logspb.LogsData]{msg: msg})
+	}
+	if err != nil {
+		r.handleError(err)
+	}
+
+	// Plain publishes to the subject expect no answer
+	if msg.Reply == "" {
+		return
+	}
+	reply, err := exportReply(msg, signal, err)
+	if err != nil {
+		r.handleError(fmt.Errorf("failed to build reply: %w", err))
+		return
+	}
+	if err := msg.RespondMsg(reply); err != nil {
+		r.handleError(fmt.Errorf("failed to reply: %w", err))
+	}
+}
+
+// exportReply builds the reply to a request whose handling returned err.
+// Batches the pipeline rejected permanently are reported through OTLP partial
+// success; any other failure is reported in the error headers, and marked
+// permanent if the request itself could not be decoded. A claim-check object
+// that could not be fetched is worth retrying.
+func exportReply(msg *nats.Msg, signal string, err error) (*nats.Msg, error) {
+	reply := nats.NewMsg(msg.Reply)
+
+	var rejected int64
+	var errMsg string
+	if err != nil {
+		var rerr receiverError
+		if !errors.As(err, &rerr) || !consumererror.IsPermanent(rerr.err) {
+			reply.Header.Set(internalnats.HeaderExportError, err.Error())
+			var derr decodeError
+			if errors.As(err, &derr) || errors.Is(err, otelnats.ErrUnknownSignal) {
+				reply.Header.Set(internalnats.HeaderExportErrorPermanent, "true")
+			}
+			return reply, nil
+		}
+		rejected, errMsg = rejectedItems(signal, rerr), rerr.err.Error()
+	}
+
+	contentType := msg.Header.Get(otelnats.HeaderContentType)
+	marshal := func(marshalProto, marshalJSON func() ([]byte, error)) ([]byte, error) {
+		if contentType == otelnats.ContentTypeJSON {
+			return marshalJSON()
+		}
+		return marshalProto()
+	}
+
+	var data []byte
+	switch signal {
+	case otelnats.SignalTraces:
+		resp := ptraceotlp.NewExportResponse()
+		if rejected > 0 {
+			resp.PartialSuccess().SetRejectedSpans(rejected)
+			resp.PartialSuccess().SetErrorMessage(errMsg)
+		}
+		data, err = marshal(resp.MarshalProto, resp.MarshalJSON)
+	case otelnats.SignalMetrics:
+		resp := pmetricotlp.NewExportResponse()
+		if rejected > 0 {
+			resp.PartialSuccess().SetRejectedDataPoints(rejected)
+			resp.PartialSuccess().SetErrorMessage(errMsg)
+		}
+		data, err = marshal(resp.MarshalProto, resp.MarshalJSON)
+	default:
+		resp := plogotlp.NewExportResponse()
+		if rejected > 0 {
+			resp.PartialSuccess().SetRejectedLogRecords(rejected)
+			resp.PartialSuccess().SetErrorMessage(errMsg)
+		}
+		data, err = marshal(resp.MarshalProto, resp.MarshalJSON)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if contentType != otelnats.ContentTypeJSON {
+		contentType = otelnats.ContentTypeProtobuf
+	}
+	reply.Data = data
+	reply.Header.Set(otelnats.HeaderContentType, contentType)
+	return reply, nil
+}
+
+// rejectedItems counts the items of a failed batch that were not consumed:
+// only the failed part of a partial failure, otherwise the whole batch.
+func rejectedItems(signal string, rerr receiverError) int64 {
+	switch signal {
+	case otelnats.SignalTraces:
+		var partial consumererror.Traces
+		if errors.As(rerr.err, &partial) {
+			return int64(partial.Data().SpanCount())
+		}
+	case otelnats.SignalMetrics:
+		var partial consumererror.Metrics
+		if errors.As(rerr.err, &partial) {
+			return int64(partial.Data().DataPointCount())
+		}
+	default:
+		var partial consumererror.Logs
+		if errors.As(rerr.err, &partial) {
+			return int64(partial.Data().LogRecordCount())
+		}
+	}
+	return int64(rerr.items)
+}
@@ -0,0 +1,58 @@
+package natsreceiver
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/mikluko/otelnats-collector/internal/metadata"
+)
+
+// Acknowledgement types recorded by otelcol_receiver_nats_acknowledgements.
+var (
+	ackAttrs  = metric.WithAttributeSet(attribute.NewSet(attribute.String("type", "ack")))
+	nakAttrs  = metric.WithAttributeSet(attribute.NewSet(attribute.String("type", "nak")))
+	termAttrs = metric.WithAttributeSet(attribute.NewSet(attribute.String("type", "term")))
+
+	inProgressAttrs = metric.WithAttributeSet(attribute.NewSet(attribute.String("type", "in_progress")))
+)
+
+// meteredMsg counts the acknowledgements sent for a message.
+type meteredMsg struct {
+	jetstream.Msg
+	telemetry *metadata.TelemetryBuilder
+}
+
+func (m *meteredMsg) Ack() error {
+	return m.acknowledged(m.Msg.Ack(), ackAttrs)
+}
+
+func (m *meteredMsg) Nak() error {
+	return m.acknowledged(m.Msg.Nak(), nakAttrs)
+}
+
+func (m *meteredMsg) NakWithDelay(delay time.Duration) error {
+	return m.acknowledged(m.Msg.NakWithDelay(delay), nakAttrs)
+}
+
+func (m *meteredMsg) InProgress() error {
+	if err := m.Msg.InProgress(); err != nil {
+		return err
+	}
+	m.telemetry.ReceiverNatsAcknowledgements.Add(context.Background(), 1, inProgressAttrs)
+	return nil
+}
+
+func (m *meteredMsg) Term() error {
+	return m.acknowledged(m.Msg.Term(), termAttrs)
+}
+
+func (m *meteredMsg) acknowledged(err error, attrs metric.AddOption) error {
+	if err == nil {
+		m.telemetry.ReceiverNatsAcknowledgements.Add(context.Background(), 1, attrs)
+	}
+	return err
+}
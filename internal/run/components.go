@@ -9,6 +9,7 @@ import (
 	"github.com/open-telemetry/opentelemetry-collector-contrib/extension/k8sleaderelector"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/extension/oauth2clientauthextension"
 	"github.com/open-telemetry/opentelemetry-collector-contrib/extension/pprofextension"
+	"github.com/open-telemetry/opentelemetry-collector-contrib/extension/storage/filestorage"
 
 	// Contrib processors
 	"github.com/open-telemetry/opentelemetry-collector-contrib/processor/attributesprocessor"
@@ -54,6 +55,7 @@ import (
 
 	// Custom components
 	"github.com/mikluko/otelnats-collector/internal/natsexporter"
+	"github.com/mikluko/otelnats-collector/internal/natsextension"
 	"github.com/mikluko/otelnats-collector/internal/natsreceiver"
 )
 
@@ -71,6 +73,8 @@ func components() (otelcol.Factories, error) {
 		oauth2clientauthextension.NewFactory(),
 		headerssetterextension.NewFactory(),
 		k8sleaderelector.NewFactory(),
+		filestorage.NewFactory(),
+		natsextension.NewFactory(),
 	)
 	if err != nil {
 		return otelcol.Factories{}, err
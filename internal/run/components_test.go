@@ -21,7 +21,7 @@ func TestComponents_AllExpectedExtensions(t *testing.T) {
 	factories, err := components()
 	require.NoError(t, err)
 
-	expectedExtensions := []string{"health_check", "zpages"}
+	expectedExtensions := []string{"health_check", "zpages", "file_storage", "nats"}
 	for _, ext := range expectedExtensions {
 		_, ok := factories.Extensions[component.MustNewType(ext)]
 		assert.True(t, ok, "extension %s should be registered", ext)
@@ -0,0 +1,47 @@
+package testutil
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componentstatus"
+)
+
+// Host is a component.Host for tests that exposes a set of extensions and
+// records the status events reported by components
+type Host struct {
+	extensions map[component.ID]component.Component
+
+	mu     sync.Mutex
+	events []*componentstatus.Event
+}
+
+var _ componentstatus.Reporter = (*Host)(nil)
+
+// NewHost creates a Host exposing the given extensions
+func NewHost(extensions map[component.ID]component.Component) *Host {
+	return &Host{extensions: extensions}
+}
+
+// GetExtensions implements component.Host
+func (h *Host) GetExtensions() map[component.ID]component.Component {
+	return h.extensions
+}
+
+// Report implements componentstatus.Reporter
+func (h *Host) Report(event *componentstatus.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, event)
+}
+
+// Statuses returns the statuses reported so far, in order
+func (h *Host) Statuses() []componentstatus.Status {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	statuses := make([]componentstatus.Status, len(h.events))
+	for i, ev := range h.events {
+		statuses[i] = ev.Status()
+	}
+	return statuses
+}
@@ -1,6 +1,7 @@
 package testutil
 
 import (
+	"net"
 	"testing"
 	"time"
 
@@ -11,13 +12,58 @@ import (
 // StartEmbeddedNATS starts an embedded NATS server for testing
 func StartEmbeddedNATS(t *testing.T) *server.Server {
 	t.Helper()
-	opts := &server.Options{
+	return startEmbeddedNATS(t, defaultOptions())
+}
+
+// StartEmbeddedNATSWithJetStream starts an embedded NATS server with
+// JetStream enabled, storing data in a per-test temporary directory
+func StartEmbeddedNATSWithJetStream(t *testing.T) *server.Server {
+	t.Helper()
+	opts := defaultOptions()
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	return startEmbeddedNATS(t, opts)
+}
+
+// StartEmbeddedNATSWithMaxPayload starts an embedded NATS server that
+// rejects messages larger than maxPayload bytes
+func StartEmbeddedNATSWithMaxPayload(t *testing.T, maxPayload int32) *server.Server {
+	t.Helper()
+	opts := defaultOptions()
+	opts.MaxPayload = maxPayload
+	return startEmbeddedNATS(t, opts)
+}
+
+// StartEmbeddedNATSOnPort starts an embedded NATS server listening on
+// port, e.g. one reserved with FreePort before the server is due to start
+func StartEmbeddedNATSOnPort(t *testing.T, port int) *server.Server {
+	t.Helper()
+	opts := defaultOptions()
+	opts.Port = port
+	return startEmbeddedNATS(t, opts)
+}
+
+// FreePort returns a TCP port on the loopback interface that is currently free
+func FreePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func defaultOptions() *server.Options {
+	return &server.Options{
 		Host:           "127.0.0.1",
 		Port:           -1, // Random available port
 		NoLog:          true,
 		NoSigs:         true,
 		MaxControlLine: 4096,
 	}
+}
+
+func startEmbeddedNATS(t *testing.T, opts *server.Options) *server.Server {
+	t.Helper()
 	ns, err := server.NewServer(opts)
 	require.NoError(t, err)
 
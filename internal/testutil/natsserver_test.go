@@ -1,10 +1,12 @@
 package testutil
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -49,3 +51,29 @@ func TestStartEmbeddedNATS(t *testing.T) {
 		t.Fatal("timeout waiting for message")
 	}
 }
+
+func TestStartEmbeddedNATSWithJetStream(t *testing.T) {
+	ns := StartEmbeddedNATSWithJetStream(t)
+	require.NotNil(t, ns)
+	assert.True(t, ns.JetStreamEnabled())
+
+	nc, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer nc.Close()
+
+	js, err := jetstream.New(nc)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:     "TEST",
+		Subjects: []string{"test.>"},
+	})
+	require.NoError(t, err)
+
+	ack, err := js.Publish(ctx, "test.subject", []byte("hello jetstream"))
+	require.NoError(t, err)
+	assert.Equal(t, "TEST", ack.Stream)
+}